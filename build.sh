@@ -46,11 +46,11 @@ echo "Verifying dependencies..."
 go mod verify
 
 # ================= 关键修改点 2: 编译命令 =================
-# 编译应用程序，使用 libsqlite3 标签来适配 Alpine/musl
+# 编译应用程序，使用 libsqlite3 标签来适配 Alpine/musl，sqlite_fts5 启用全文搜索
 echo "Building application..."
 echo "This may take a few minutes..."
 go build -v \
-    -tags="libsqlite3" \
+    -tags="libsqlite3 sqlite_fts5" \
     -ldflags="-w -s" \
     -o quiz-system \
     main.go
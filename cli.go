@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"quiz-system/services"
+)
+
+// runCommand 执行命令行子命令，如 export、import
+func runCommand(args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	}
+	printUsage()
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+// printUsage 打印命令行用法
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `用法:
+  quiz-system                          启动Web服务
+  quiz-system export [选项]            导出题库
+      -format 格式                     导出格式：json（默认）、csv、markdown、
+                                       qti（QTI 2.1内容包）、gift、aiken、
+                                       bundle（附带附件的压缩包）
+      -category 分类                   仅导出指定分类
+      -type single|multiple|judge      仅导出指定题型
+      -o 文件                          输出文件（默认标准输出）
+  quiz-system import [选项] 文件       导入题库
+      -format 格式                     文件格式：json、csv、qti、gift、aiken、bundle
+                                       （默认按扩展名判断）
+      -update                          ID已存在的题目按编辑处理并生成新版本`)
+}
+
+// initServices 初始化命令行所需的数据库和缓存
+func initServices() error {
+	if err := services.InitDatabase(); err != nil {
+		return fmt.Errorf("failed to initialize database: %v", err)
+	}
+	if err := services.InitCache(); err != nil {
+		return fmt.Errorf("failed to initialize cache: %v", err)
+	}
+	return nil
+}
+
+// runExport 导出题库
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", services.FormatJSON, "export format")
+	category := fs.String("category", "", "category filter")
+	qType := fs.String("type", "", "question type filter")
+	output := fs.String("o", "", "output file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, _, err := services.ExportContentType(*format); err != nil {
+		return err
+	}
+	if err := initServices(); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	bw := bufio.NewWriter(w)
+	filter := services.QuestionFilter{Category: *category, Type: *qType}
+	if err := services.ExportQuestions(bw, *format, filter); err != nil {
+		return fmt.Errorf("export failed: %v", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		log.Printf("Exported questions to %s", *output)
+	}
+	return nil
+}
+
+// runImport 从文件导入题库
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "import format")
+	update := fs.Bool("update", false, "update questions with existing IDs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		printUsage()
+		return fmt.Errorf("import requires exactly one file")
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = services.FormatFromFilename(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	questions, err := services.ParseQuestions(file, *format)
+	if err != nil {
+		return err
+	}
+
+	if err := initServices(); err != nil {
+		return err
+	}
+
+	result, err := services.ImportQuestions(questions, *update, 0)
+	if err != nil {
+		return fmt.Errorf("import failed: %v", err)
+	}
+
+	log.Printf("Imported %s: %d created, %d updated, %d unchanged",
+		path, result.Created, result.Updated, result.Unchanged)
+	return nil
+}
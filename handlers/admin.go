@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateQuestion 编辑题目（生成新版本）
+func UpdateQuestion(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	var req models.QuestionUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	question, revision, err := services.UpdateQuestion(uint(id), &req, userSession.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Question not found",
+			})
+		case errors.Is(err, services.ErrNoQuestionChanges), errors.Is(err, services.ErrEmptyAnswer),
+			errors.Is(err, services.ErrInvalidDifficulty), errors.Is(err, services.ErrInvalidGrading),
+			errors.Is(err, services.ErrAttachmentMissing):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update question",
+			})
+		}
+		return
+	}
+
+	response := gin.H{
+		"question": question,
+		"revision": revision,
+	}
+
+	// 按需立即重新判分
+	if req.Regrade {
+		result, err := services.RegradeQuestion(question.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Question updated but regrade failed",
+			})
+			return
+		}
+		response["regrade"] = result
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RegradeQuestion 按当前答案重新判分单道题目的历史记录
+func RegradeQuestion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	result, err := services.RegradeQuestion(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Question not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to regrade answers",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// StartRegradeJob 启动全量重新判分任务
+func StartRegradeJob(c *gin.Context) {
+	if err := services.StartRegradeJob(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, services.GetRegradeJobStatus())
+}
+
+// GetRegradeJobStatus 获取重新判分任务状态
+func GetRegradeJobStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetRegradeJobStatus())
+}
+
+// ImportQuestions 上传题库文件导入（json、csv、qti、gift、aiken）
+func ImportQuestions(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	// 限制请求体大小（留出multipart表单的额外开销），避免上传过大的文件占满内存和磁盘
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImportFileSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": services.ErrImportFileTooLarge.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "File is required",
+		})
+		return
+	}
+	if fileHeader.Size > services.MaxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": services.ErrImportFileTooLarge.Error(),
+		})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = services.FormatFromFilename(fileHeader.Filename)
+	}
+	update := c.Query("update") == "true"
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+	defer file.Close()
+
+	questions, err := services.ParseQuestions(file, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to parse question file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 导入失败时整个导入回滚，不保留部分结果
+	result, err := services.ImportQuestions(questions, update, userSession.UserID)
+	if err != nil {
+		if services.IsImportDataError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid question data",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import questions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"format": format,
+		"result": result,
+	})
+}
+
+// GetDuplicateClusters 近似重复题目报告，支持 threshold（0.5-1，默认0.8）、category、limit、offset
+func GetDuplicateClusters(c *gin.Context) {
+	threshold := services.DefaultDuplicateThreshold
+	if s := c.Query("threshold"); s != "" {
+		var err error
+		if threshold, err = strconv.ParseFloat(s, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": services.ErrInvalidThreshold.Error(),
+			})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	clusters, err := services.GetDuplicateClusters(threshold, c.Query("category"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidThreshold) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to detect duplicate questions",
+		})
+		return
+	}
+
+	duplicates := 0
+	for _, cluster := range clusters {
+		duplicates += cluster.Size
+	}
+
+	total := len(clusters)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":  clusters[offset:end],
+		"total":     total,
+		"questions": duplicates,
+		"threshold": threshold,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAttachment 获取附件内容
+// 附件按内容寻址，内容不变，响应可长期缓存；支持If-None-Match和Range请求
+func GetAttachment(c *gin.Context) {
+	attachment, err := services.GetAttachment(c.Param("sha256"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attachment not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get attachment",
+		})
+		return
+	}
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("ETag", fmt.Sprintf(`"%s"`, attachment.SHA256))
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, attachment.Filename))
+	http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.CreatedAt, bytes.NewReader(attachment.Data))
+}
+
+// UploadAttachment 上传附件（multipart字段file），返回可写入题目的引用
+func UploadAttachment(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "File is required",
+		})
+		return
+	}
+	if fileHeader.Size > services.MaxAttachmentSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": services.ErrAttachmentTooLarge.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxAttachmentSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+
+	attachment, err := services.SaveAttachment(fileHeader.Filename, data, userSession.UserID)
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentTooLarge) || errors.Is(err, services.ErrUnsupportedAttachment) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save attachment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"attachment": attachment,
+		"reference":  services.AttachmentReference(attachment),
+	})
+}
+
+// ListAttachments 获取附件列表及引用它们的题目，支持 limit、offset
+func ListAttachments(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	attachments, total, err := services.ListAttachments(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get attachments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attachments": attachments,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// DeleteAttachment 删除未被题目引用的附件
+func DeleteAttachment(c *gin.Context) {
+	err := services.DeleteAttachment(c.Param("sha256"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Attachment deleted",
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Attachment not found",
+		})
+	case errors.Is(err, services.ErrAttachmentInUse):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete attachment",
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AddBookmark 收藏题目
+func AddBookmark(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	if err := services.AddBookmark(userSession.UserID, uint(id)); err != nil {
+		respondPersonalDataError(c, err, "Failed to add bookmark")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": id,
+		"bookmarked":  true,
+	})
+}
+
+// RemoveBookmark 取消收藏题目
+func RemoveBookmark(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	if err := services.RemoveBookmark(userSession.UserID, uint(id)); err != nil {
+		respondPersonalDataError(c, err, "Failed to remove bookmark")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": id,
+		"bookmarked":  false,
+	})
+}
+
+// SaveNote 保存题目的个人笔记，内容为空时删除
+func SaveNote(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	var req models.NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	note, err := services.SaveNote(userSession.UserID, uint(id), req.Content)
+	if err != nil {
+		respondPersonalDataError(c, err, "Failed to save note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": id,
+		"note":        note,
+	})
+}
+
+// DeleteNote 删除题目的个人笔记
+func DeleteNote(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	if err := services.DeleteNote(userSession.UserID, uint(id)); err != nil {
+		respondPersonalDataError(c, err, "Failed to delete note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note deleted",
+	})
+}
+
+// GetBookmarks 获取收藏的题目列表，支持 category、limit、offset
+func GetBookmarks(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	questions, total, err := services.GetBookmarks(userSession.UserID, c.Query("category"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get bookmarks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": questions,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// StartBookmarkPractice 从收藏的题目中随机抽题开始练习，支持 category、limit
+func StartBookmarkPractice(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	questions, err := services.GetRandomBookmarkedQuestions(userSession.UserID, c.Query("category"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get bookmarked questions",
+		})
+		return
+	}
+	if len(questions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No bookmarked questions",
+		})
+		return
+	}
+
+	createExamSession(c, userSession.UserID, "bookmark", questions, 0, nil)
+}
+
+// respondPersonalDataError 将书签和笔记相关错误转换为HTTP响应
+func respondPersonalDataError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Question not found",
+		})
+	case errors.Is(err, services.ErrNoteTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
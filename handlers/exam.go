@@ -3,6 +3,7 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -23,58 +24,123 @@ func StartExam(c *gin.Context) {
 	}
 
 	userSession := user.(*services.UserSession)
-	examType := c.Query("type") // practice 或 mock_exam
+	examType := c.Query("type") // practice、mock_exam 或 wrong_questions（错题组卷）
 	
 	if examType == "" {
 		examType = "practice"
 	}
 
-	var questions []models.Question
-	var duration int // 考试时长（分钟）
-	
-	if examType == "mock_exam" {
-		// 模拟考试：单选60题、多选60题、判断60题，共180题，180分钟
-		singleQuestions, err := services.Cache.GetRandomQuestions("single", 60)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get single choice questions",
-			})
-			return
-		}
-		
-		multipleQuestions, err := services.Cache.GetRandomQuestions("multiple", 60)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get multiple choice questions",
+	// 请求体可提供自定义组卷规则
+	var req models.ExamStartRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request data",
 			})
 			return
 		}
-		
-		judgeQuestions, err := services.Cache.GetRandomQuestions("judge", 60)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get judge questions",
+	}
+
+	if examType == services.WrongExamType {
+		startWrongQuestionExam(c, userSession.UserID, req.WrongQuestions)
+		return
+	}
+
+	blueprint := req.Blueprint
+	if blueprint != nil {
+		examType = "custom"
+		if err := services.ValidateBlueprint(blueprint); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
 			})
 			return
 		}
-		
-		questions = append(questions, singleQuestions...)
-		questions = append(questions, multipleQuestions...)
-		questions = append(questions, judgeQuestions...)
-		duration = 180 // 180分钟
 	} else {
-		// 练习模式：随机20题，不限时间
-		var err error
-		questions, err = services.Cache.GetRandomQuestions("", 20)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to get practice questions",
+		var ok bool
+		if blueprint, ok = services.GetExamBlueprint(examType); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unknown exam type",
 			})
 			return
 		}
-		duration = 0 // 不限时间
 	}
 
+	// 查询参数中的分类、标签、难度进一步限定组卷范围
+	filter, err := questionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	services.NarrowBlueprint(blueprint, filter)
+
+	questions, shortfalls, err := services.SelectBlueprintQuestions(blueprint)
+	if errors.Is(err, services.ErrNoBlueprintQuestions) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get exam questions",
+		})
+		return
+	}
+	duration := blueprint.Duration // 考试时长（分钟），0为不限时
+
+	extra := gin.H{
+		"blueprint": blueprint,
+	}
+	if len(shortfalls) > 0 {
+		extra["shortfalls"] = shortfalls
+	}
+	createExamSession(c, userSession.UserID, examType, questions, duration, extra)
+}
+
+// startWrongQuestionExam 错题组卷：从用户答错过的题目中按答错次数和最近答错时间加权抽题
+// 查询参数中的分类、标签、难度进一步限定抽题范围
+func startWrongQuestionExam(c *gin.Context, userID uint, options *models.WrongExamOptions) {
+	if options == nil {
+		options = &models.WrongExamOptions{}
+	}
+	if err := services.ValidateWrongExamOptions(options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	filter, err := questionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	questions, err := services.SelectWrongExamQuestions(userID, options, filter)
+	if errors.Is(err, services.ErrNoWrongQuestions) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get exam questions",
+		})
+		return
+	}
+
+	createExamSession(c, userID, services.WrongExamType, questions, *options.Duration, gin.H{
+		"wrong_questions": options,
+	})
+}
+
+// createExamSession 创建考试会话和考试记录并返回题目，extra中的字段附加到响应
+func createExamSession(c *gin.Context, userID uint, examType string, questions []models.Question, duration int, extra gin.H) {
 	// 生成考试会话ID
 	sessionID, err := generateExamSessionID()
 	if err != nil {
@@ -90,22 +156,18 @@ func StartExam(c *gin.Context) {
 		questionIDs[i] = q.ID
 	}
 
-	// 创建考试会话
-	examSession := &models.ExamSession{
-		ID:          sessionID,
-		UserID:      userSession.UserID,
-		Questions:   questionIDs,
-		Answers:     make(map[uint]string),
-		StartTime:   time.Now(),
-		Duration:    duration,
-		IsCompleted: false,
+	// 附加书签和笔记
+	views, err := services.AttachPersonalData(userID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get exam questions",
+		})
+		return
 	}
 
-	services.Cache.SetExamSession(sessionID, examSession)
-
 	// 创建考试记录
 	examRecord := models.ExamRecord{
-		UserID:       userSession.UserID,
+		UserID:       userID,
 		ExamType:     examType,
 		TotalCount:   len(questions),
 		CorrectCount: 0,
@@ -120,13 +182,31 @@ func StartExam(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// 创建考试会话
+	examSession := &models.ExamSession{
+		ID:          sessionID,
+		UserID:      userID,
+		Questions:   questionIDs,
+		Answers:     make(map[uint]string),
+		StartTime:   time.Now(),
+		Duration:    duration,
+		IsCompleted: false,
+		RecordID:    examRecord.ID,
+	}
+
+	services.Cache.SetExamSession(sessionID, examSession)
+
+	response := gin.H{
 		"session_id": sessionID,
 		"exam_type":  examType,
-		"questions":  questions,
+		"questions":  views,
 		"duration":   duration,
 		"start_time": examSession.StartTime,
-	})
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetExamSession 获取考试会话
@@ -168,6 +248,15 @@ func GetExamSession(c *gin.Context) {
 		questions = append(questions, *question)
 	}
 
+	// 附加书签和笔记
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get exam questions",
+		})
+		return
+	}
+
 	// 计算剩余时间
 	var remainingTime int
 	if examSession.Duration > 0 {
@@ -180,7 +269,7 @@ func GetExamSession(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"session":        examSession,
-		"questions":      questions,
+		"questions":      views,
 		"remaining_time": remainingTime,
 	})
 }
@@ -279,13 +368,32 @@ func CompleteExam(c *gin.Context) {
 		return
 	}
 
-	// 标记考试完成
-	examSession.IsCompleted = true
-	services.Cache.UpdateExamSession(sessionID, examSession)
+	// 标记考试完成，重复交卷时不再保存答题记录
+	if !services.Cache.CompleteExamSession(sessionID) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Exam already completed",
+		})
+		return
+	}
+
+	// 查找考试记录，答题记录关联到该记录以便人工复核后更新成绩
+	var examRecord models.ExamRecord
+	recordQuery := services.DB.Where("user_id = ?", userSession.UserID)
+	if examSession.RecordID != 0 {
+		recordQuery = recordQuery.Where("id = ?", examSession.RecordID)
+	} else {
+		recordQuery = recordQuery.Where("started_at >= ?", examSession.StartTime.Add(-time.Minute)).
+			Order("started_at DESC")
+	}
+	hasRecord := recordQuery.First(&examRecord).Error == nil
 
 	// 计算成绩
 	correctCount := 0
-	totalCount := len(examSession.Questions)
+	pendingCount := 0
+	totalScore := 0.0
+	totalCount := 0 // 实际判分并保存的题数，已删除的题目不计入
+	var reviewQuestions []models.Question
+	var reviewAnswers []models.UserAnswer
 	
 	// 保存所有答题记录
 	for _, questionID := range examSession.Questions {
@@ -300,42 +408,40 @@ func CompleteExam(c *gin.Context) {
 			continue
 		}
 
-		// 判断答案是否正确
-		isCorrect := false
+		// 判分，未答题不得分
+		var grade models.GradeResult
 		if hasAnswer {
-			switch question.Type {
-			case "single", "judge":
-				isCorrect = userAnswer == question.Answer
-			case "multiple":
-				isCorrect = compareMultipleChoiceAnswer(userAnswer, question.Answer)
-			}
+			grade = services.GradeAnswer(question, userAnswer)
 		}
 
-		if isCorrect {
-			correctCount++
+		// 保存答题记录，保存失败的题目不计入成绩，与复核后按答题记录重算的成绩一致
+		userAnswerRecord, err := services.SaveUserAnswer(userSession.UserID, question, userAnswer, grade, examRecord.ID)
+		if err != nil {
+			continue
 		}
 
-		// 保存答题记录
-		userAnswerRecord := models.UserAnswer{
-			UserID:     userSession.UserID,
-			QuestionID: questionID,
-			UserAnswer: userAnswer,
-			IsCorrect:  isCorrect,
-			Category:   question.Category,
-			AnsweredAt: time.Now(),
+		totalCount++
+		if grade.IsCorrect {
+			correctCount++
 		}
-		services.DB.Create(&userAnswerRecord)
+		if grade.ReviewStatus == services.ReviewStatusPending {
+			pendingCount++
+		}
+		totalScore += grade.Score
+
+		reviewQuestions = append(reviewQuestions, *question)
+		reviewAnswers = append(reviewAnswers, *userAnswerRecord)
 	}
 
-	// 计算分数
-	score := float64(correctCount) / float64(totalCount) * 100
+	// 计算分数，填空题和简答题按得分比例计分
+	score := 0.0
+	if totalCount > 0 {
+		score = totalScore / float64(totalCount) * 100
+	}
 
 	// 更新考试记录
-	var examRecord models.ExamRecord
-	if err := services.DB.Where("user_id = ? AND started_at >= ?", 
-		userSession.UserID, examSession.StartTime.Add(-time.Minute)).
-		Order("started_at DESC").First(&examRecord).Error; err == nil {
-		
+	if hasRecord {
+		examRecord.TotalCount = totalCount
 		examRecord.CorrectCount = correctCount
 		examRecord.Score = score
 		examRecord.Duration = int(time.Since(examSession.StartTime).Seconds())
@@ -349,10 +455,34 @@ func CompleteExam(c *gin.Context) {
 		"total_questions": totalCount,
 		"correct_answers": correctCount,
 		"score":          score,
+		"pending_review": pendingCount, // 等待人工复核的题数，复核后更新考试成绩
 		"duration":       int(time.Since(examSession.StartTime).Minutes()),
 		"completed_at":   time.Now(),
 	}
 
+	// 逐题回顾：作答情况、书签和笔记
+	if views, err := services.AttachPersonalData(userSession.UserID, reviewQuestions); err == nil {
+		questionIDs := make([]uint, len(views))
+		for i := range views {
+			questionIDs[i] = views[i].ID
+		}
+		// 关联的标准条款原文摘录，查询失败时不影响回顾
+		excerpts, _ := services.GetClauseExcerpts(questionIDs)
+
+		review := make([]models.ExamReviewItem, len(views))
+		for i := range views {
+			review[i] = models.ExamReviewItem{
+				QuestionView: views[i],
+				UserAnswer:   reviewAnswers[i].UserAnswer,
+				IsCorrect:    reviewAnswers[i].IsCorrect,
+				Score:        reviewAnswers[i].Score,
+				ReviewStatus: reviewAnswers[i].ReviewStatus,
+				Clauses:      excerpts[views[i].ID],
+			}
+		}
+		result["review"] = review
+	}
+
 	// 清理考试会话
 	services.Cache.DeleteExamSession(sessionID)
 
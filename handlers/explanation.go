@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetQuestionExplanations 获取题目的社区解析
+func GetQuestionExplanations(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	explanations, err := services.GetQuestionExplanations(uint(id), userSession.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get explanations",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"explanations": explanations,
+		"total":        len(explanations),
+	})
+}
+
+// SubmitExplanation 提交或更新自己对题目的社区解析
+func SubmitExplanation(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	var req models.ExplanationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	explanation, err := services.SubmitExplanation(userSession.UserID, uint(id), req.Content)
+	if err != nil {
+		respondExplanationError(c, err, "Question not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// VoteExplanation 对社区解析投票
+func VoteExplanation(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, ok := parseExplanationID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ExplanationVoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	explanation, err := services.VoteExplanation(userSession.UserID, id, req.Value)
+	if err != nil {
+		respondExplanationError(c, err, "Explanation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// DeleteExplanation 隐藏社区解析（作者本人或管理员）
+func DeleteExplanation(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, ok := parseExplanationID(c)
+	if !ok {
+		return
+	}
+
+	if err := services.HideExplanation(id, userSession.UserID, userSession.IsAdmin); err != nil {
+		respondExplanationError(c, err, "Explanation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Explanation removed",
+	})
+}
+
+// ListExplanationCandidates 获取待采纳的社区解析（题目尚无官方解析）
+func ListExplanationCandidates(c *gin.Context) {
+	minScore := 1
+	if s, err := strconv.Atoi(c.Query("min_score")); err == nil {
+		minScore = s
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	explanations, err := services.ListExplanationCandidates(minScore, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get explanations",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"explanations": explanations,
+		"total":        len(explanations),
+	})
+}
+
+// PromoteExplanation 将社区解析采纳为官方解析
+func PromoteExplanation(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, ok := parseExplanationID(c)
+	if !ok {
+		return
+	}
+
+	explanation, revision, err := services.PromoteExplanation(id, userSession.UserID)
+	if err != nil {
+		respondExplanationError(c, err, "Explanation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"explanation": explanation,
+		"revision":    revision,
+	})
+}
+
+// parseExplanationID 解析路径中的解析ID，失败时已写入响应
+func parseExplanationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid explanation ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondExplanationError 将社区解析相关错误转换为响应
+func respondExplanationError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": notFound,
+		})
+	case errors.Is(err, services.ErrEmptyExplanation), errors.Is(err, services.ErrExplanationTooLong),
+		errors.Is(err, services.ErrInvalidVote), errors.Is(err, services.ErrSelfVote):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrNotExplanationAuthor):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process explanation",
+		})
+	}
+}
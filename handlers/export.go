@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportQuestions 导出题库（json、csv、markdown、qti、gift、aiken、bundle），筛选参数同题目列表
+func ExportQuestions(c *gin.Context) {
+	format := c.DefaultQuery("format", services.FormatJSON)
+	filter, err := questionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	contentType, ext, err := services.ExportContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("questions-%s.%s", time.Now().Format("20060102"), ext)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// 直接写入响应流，响应头已发送，出错时只能记录日志
+	if err := services.ExportQuestions(c.Writer, format, filter); err != nil {
+		log.Printf("Failed to export questions: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNotifications 获取当前用户的通知，unread=true时只返回未读通知
+func GetNotifications(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	notifications, unread, err := services.GetNotifications(userSession.UserID, c.Query("unread") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get notifications",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread":        unread,
+	})
+}
+
+// MarkNotificationRead 将通知标记为已读
+func MarkNotificationRead(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid notification ID",
+		})
+		return
+	}
+
+	if err := services.MarkNotificationRead(userSession.UserID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Notification not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update notification",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification marked as read",
+	})
+}
+
+// MarkAllNotificationsRead 将所有通知标记为已读
+func MarkAllNotificationsRead(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	count, err := services.MarkAllNotificationsRead(userSession.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update notifications",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"updated": count,
+	})
+}
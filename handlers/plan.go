@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTodayPlan 获取当天的学习计划，包括到期复习、薄弱分类强化和新题，rebuild=true 时按当前设置重新安排未完成的题目
+func GetTodayPlan(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	plan, err := services.GetTodayPlan(userSession.UserID, c.Query("rebuild") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get study plan",
+		})
+		return
+	}
+
+	// 已删除的题目不再列出
+	var questions []models.Question
+	var items []models.StudyPlanItem
+	for _, item := range plan.Items {
+		question, err := services.Cache.GetQuestion(item.QuestionID)
+		if err != nil {
+			continue
+		}
+		questions = append(questions, *question)
+		items = append(items, item)
+	}
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get study plan",
+		})
+		return
+	}
+	planQuestions := make([]models.PlanQuestion, len(views))
+	completed := 0
+	for i := range views {
+		views[i].Options = displayOptions(views[i].Options)
+		planQuestions[i] = models.PlanQuestion{QuestionView: views[i], PlanKind: items[i].Kind, Done: items[i].Done}
+		if items[i].Done {
+			completed++
+		}
+	}
+	completion := 0.0
+	if len(planQuestions) > 0 {
+		completion = float64(completed) / float64(len(planQuestions)) * 100
+	}
+
+	plan.Items = items
+	c.JSON(http.StatusOK, gin.H{
+		"date":            plan.Date,
+		"budget":          plan.Budget,
+		"days_until_exam": plan.DaysUntilExam,
+		"sections":        services.PlanSections(plan),
+		"questions":       planQuestions,
+		"total":           len(planQuestions),
+		"completed":       completed,
+		"completion":      completion,
+	})
+}
+
+// GetStudySettings 获取每日学习设置
+func GetStudySettings(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	settings, err := services.GetStudySettings(userSession.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get study settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateStudySettings 更新每日题量、每日学习时间和考试日期
+func UpdateStudySettings(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	var req models.StudySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+
+	settings, err := services.UpdateStudySettings(userSession.UserID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStudySettings) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update study settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetPlanHistory 获取最近的学习计划完成情况，支持 days（默认30，最多365）
+func GetPlanHistory(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+
+	history, streak, err := services.GetPlanHistory(userSession.UserID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get plan history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+		"streak":  streak,
+		"days":    days,
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSequentialPractice 分类顺序练习：按题目ID顺序从上次的位置继续，支持 category（必填）、limit、restart
+func GetSequentialPractice(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	category := c.Query("category")
+	if category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Category is required",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	restart := c.Query("restart") == "true"
+
+	questions, progress, err := services.GetSequentialQuestions(userSession.UserID, category, limit, restart)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptyCategory) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get questions",
+		})
+		return
+	}
+
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get questions",
+		})
+		return
+	}
+	for i := range views {
+		views[i].Options = displayOptions(views[i].Options)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": views,
+		"total":     len(views),
+		"progress":  progress,
+	})
+}
+
+// SaveSequentialPosition 保存分类顺序练习的位置，手动保存时可移到前面的题目
+func SaveSequentialPosition(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	var req models.PracticePositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	progress, err := services.SaveSequentialPosition(userSession.UserID, req.Category, req.QuestionID, false)
+	if err != nil {
+		if errors.Is(err, services.ErrQuestionNotInCategory) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save position",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"progress": progress,
+	})
+}
+
+// ResetSequentialPosition 清除分类顺序练习的位置，下次从头开始
+func ResetSequentialPosition(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	category := c.Query("category")
+	if category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Category is required",
+		})
+		return
+	}
+
+	if err := services.ResetSequentialPosition(userSession.UserID, category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset position",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Position reset",
+	})
+}
+
+// GetSequentialProgress 获取所有分类的顺序练习进度
+func GetSequentialProgress(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	progress, err := services.ListSequentialProgress(userSession.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get progress",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"progress": progress,
+	})
+}
+
+// PreviewCustomPractice 统计符合自定义练习条件的题目数，用于组卷前确认题量
+func PreviewCustomPractice(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	var req models.PracticeSetRequest
+	if !bindPracticeSetRequest(c, &req) {
+		return
+	}
+
+	preview, err := services.PreviewPracticeSet(userSession.UserID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count questions",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// StartCustomPractice 按自定义条件组卷并创建练习会话，作答、答题卡和考试历史与普通考试相同
+func StartCustomPractice(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	var req models.PracticeSetRequest
+	if !bindPracticeSetRequest(c, &req) {
+		return
+	}
+
+	questions, preview, err := services.SelectPracticeSet(userSession.UserID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrNotEnoughQuestions) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     err.Error(),
+				"available": preview.Available,
+				"requested": req.Count,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get practice questions",
+		})
+		return
+	}
+
+	createExamSession(c, userSession.UserID, services.CustomPracticeType, questions, req.Duration, gin.H{
+		"filters":   req,
+		"available": preview.Available,
+	})
+}
+
+// bindPracticeSetRequest 解析并校验自定义练习请求，失败时写入错误响应并返回false
+func bindPracticeSetRequest(c *gin.Context, req *models.PracticeSetRequest) bool {
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request data",
+			})
+			return false
+		}
+	}
+	if err := services.ValidatePracticeSet(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+	return true
+}
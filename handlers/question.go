@@ -2,21 +2,37 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"quiz-system/models"
 	"quiz-system/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// questionFilterFromQuery 从查询参数构建题目筛选条件
+// 支持 category、type、tags（逗号分隔，需同时包含）、difficulty（如 3 或 2-4）、
+// distinct（为true时随机抽题每个近似重复簇至多一道）
+func questionFilterFromQuery(c *gin.Context) (services.QuestionFilter, error) {
+	filter := services.QuestionFilter{
+		Category:         c.Query("category"),
+		Type:             c.Query("type"),
+		Tags:             services.ParseTags(c.Query("tags")),
+		DistinctClusters: c.Query("distinct") == "true",
+	}
+
+	var err error
+	filter.MinDifficulty, filter.MaxDifficulty, err = services.ParseDifficultyRange(c.Query("difficulty"))
+	return filter, err
+}
+
 // GetQuestions 获取题目列表
 func GetQuestions(c *gin.Context) {
-	category := c.Query("category")
 	limitStr := c.Query("limit")
-	qType := c.Query("type")
 	
 	limit := 20 // 默认返回20道题
 	if limitStr != "" {
@@ -25,20 +41,25 @@ func GetQuestions(c *gin.Context) {
 		}
 	}
 
-	var questions []models.Question
-	var err error
+	filter, err := questionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	if category != "" {
-		// 按分类获取题目
-		questions, err = services.Cache.GetQuestionsByCategory(category, limit)
-	} else if qType != "" {
-		// 按类型获取随机题目
-		questions, err = services.Cache.GetRandomQuestions(qType, limit)
-	} else {
-		// 获取随机题目
-		questions, err = services.Cache.GetRandomQuestions("", limit)
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	// unseen_first为true时优先抽取未作答过的题目
+	if c.Query("unseen_first") == "true" {
+		filter.UnseenFirstUserID = userSession.UserID
 	}
 
+	// 指定分类时按顺序返回，否则随机抽取；优先未作答过的题目时总是随机抽取
+	random := filter.Category == "" || filter.UnseenFirstUserID > 0
+	questions, err := services.Cache.FindQuestions(filter, limit, random)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get questions",
@@ -46,20 +67,23 @@ func GetQuestions(c *gin.Context) {
 		return
 	}
 
-	// 处理选项字段
-	for i := range questions {
-		if questions[i].Options != "" {
-			var options []string
-			if err := json.Unmarshal([]byte(questions[i].Options), &options); err == nil {
-				// 临时存储解析后的选项，用于前端显示
-				questions[i].Options = strings.Join(options, "|")
-			}
-		}
+	// 附加书签和笔记
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get questions",
+		})
+		return
+	}
+
+	// 处理选项字段（在副本上修改，避免影响缓存中的题目）
+	for i := range views {
+		views[i].Options = displayOptions(views[i].Options)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"questions": questions,
-		"total": len(questions),
+		"questions": views,
+		"total": len(views),
 	})
 }
 
@@ -82,16 +106,71 @@ func GetQuestion(c *gin.Context) {
 		return
 	}
 
+	// 附加书签和笔记
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+	views, err := services.AttachPersonalData(userSession.UserID, []models.Question{*question})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get question",
+		})
+		return
+	}
+
 	// 处理选项字段
-	if question.Options != "" {
-		var options []string
-		if err := json.Unmarshal([]byte(question.Options), &options); err == nil {
-			question.Options = strings.Join(options, "|")
-		}
+	view := views[0]
+	view.Options = displayOptions(view.Options)
+
+	// 关联的标准条款
+	clauses, err := services.GetQuestionClauses(view.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get question",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question": view,
+		"clauses":  clauses,
+	})
+}
+
+// displayOptions 将JSON数组格式的选项转换为"|"分隔，用于前端显示
+func displayOptions(options string) string {
+	if options == "" {
+		return options
+	}
+	var parsed []string
+	if err := json.Unmarshal([]byte(options), &parsed); err != nil {
+		return options
+	}
+	return strings.Join(parsed, "|")
+}
+
+// GetQuestionChangelog 获取题目的版本历史
+func GetQuestionChangelog(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	revisions, err := services.GetQuestionChangelog(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Question not found",
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"question": question,
+		"question_id": id,
+		"revisions":   revisions,
+		"total":       len(revisions),
 	})
 }
 
@@ -111,6 +190,37 @@ func GetCategories(c *gin.Context) {
 	})
 }
 
+// GetCategoryTree 获取分类树及各节点题目数
+func GetCategoryTree(c *gin.Context) {
+	tree, err := services.GetCategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get category tree",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": tree,
+	})
+}
+
+// GetTags 获取标签列表及题目数，可按分类筛选
+func GetTags(c *gin.Context) {
+	tags, err := services.GetTags(c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":  tags,
+		"total": len(tags),
+	})
+}
+
 // SubmitAnswer 提交答案
 func SubmitAnswer(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -140,47 +250,53 @@ func SubmitAnswer(c *gin.Context) {
 		return
 	}
 
-	// 判断答案是否正确
+	// 判分
 	userAnswer := strings.TrimSpace(req.Answer)
-	correctAnswer := strings.TrimSpace(question.Answer)
-	isCorrect := false
-
-	switch question.Type {
-	case "single", "judge":
-		isCorrect = strings.EqualFold(userAnswer, correctAnswer)
-	case "multiple":
-		// 多选题需要特殊处理
-		isCorrect = compareMultipleChoiceAnswer(userAnswer, correctAnswer)
-	}
+	grade := services.GradeAnswer(question, userAnswer)
 
 	// 保存答题记录
-	userAnswerRecord := models.UserAnswer{
-		UserID:     userSession.UserID,
-		QuestionID: req.QuestionID,
-		UserAnswer: userAnswer,
-		IsCorrect:  isCorrect,
-		Category:   question.Category,
-		AnsweredAt: time.Now(),
-	}
-
-	if err := services.DB.Create(&userAnswerRecord).Error; err != nil {
+	if _, err := services.SaveUserAnswer(userSession.UserID, question, userAnswer, grade, 0); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to save answer",
 		})
 		return
 	}
 
-	// 返回答题结果
+	// 顺序练习时记录位置，只向后移动，失败不影响作答结果
+	if req.SequentialCategory != "" {
+		if _, err := services.SaveSequentialPosition(userSession.UserID, req.SequentialCategory, question.ID, true); err != nil {
+			log.Printf("Failed to save sequential position for user %d: %v", userSession.UserID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, answerResponse(question, grade))
+}
+
+// answerResponse 构造答题结果：正确答案、解析及关联条款摘录
+func answerResponse(question *models.Question, grade models.GradeResult) models.AnswerResponse {
 	response := models.AnswerResponse{
-		IsCorrect:     isCorrect,
-		CorrectAnswer: correctAnswer,
+		GradeResult:   grade,
+		CorrectAnswer: strings.TrimSpace(question.Answer),
 		Explanation:   question.Explanation,
 	}
 
-	c.JSON(http.StatusOK, response)
+	// 没有官方解析时使用得分最高的社区解析
+	if response.Explanation != "" {
+		response.ExplanationSource = "official"
+	} else if community, err := services.TopCommunityExplanation(question.ID); err == nil && community != nil {
+		response.Explanation = community.Content
+		response.ExplanationSource = "community"
+		response.CommunityExplanationID = community.ID
+	}
+
+	// 附带关联的标准条款原文摘录
+	if excerpts, err := services.GetClauseExcerpts([]uint{question.ID}); err == nil {
+		response.Clauses = excerpts[question.ID]
+	}
+	return response
 }
 
-// GetWrongQuestions 获取错题本
+// GetWrongQuestions 获取错题本，每题一条，支持 category、status（默认active，可选graduated、removed、all）、limit、offset
 func GetWrongQuestions(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -191,10 +307,24 @@ func GetWrongQuestions(c *gin.Context) {
 	}
 
 	userSession := user.(*services.UserSession)
-	
-	// 获取用户统计信息（包含错题）
-	stats, err := services.GetUserStats(userSession.UserID)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	items, total, err := services.GetWrongBook(userSession.UserID, c.Query("category"), c.Query("status"), limit, offset)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidWrongStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get wrong questions",
 		})
@@ -202,8 +332,83 @@ func GetWrongQuestions(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"wrong_questions": stats.WrongQuestions,
-		"total": len(stats.WrongQuestions),
+		"wrong_questions":   items,
+		"total":             total,
+		"limit":             limit,
+		"offset":            offset,
+		"graduation_streak": services.WrongBookGraduationStreak,
+	})
+}
+
+// RemoveWrongQuestion 手动将题目移出错题本
+func RemoveWrongQuestion(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	if err := services.RemoveWrongQuestion(userSession.UserID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Question is not in wrong-question book",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove wrong question",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": id,
+		"status":      services.WrongStatusRemoved,
+	})
+}
+
+// GetCoverage 获取题库覆盖情况：总体及按分类、题型统计作答过和未作答的题目数，支持 category
+func GetCoverage(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	coverage, err := services.GetCoverage(userSession.UserID, c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get coverage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, coverage)
+}
+
+// GetWeakAreas 获取薄弱分类，按掌握程度从低到高排列，支持 limit（默认5，最多50）
+func GetWeakAreas(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if limit <= 0 || limit > 50 {
+		limit = 5
+	}
+
+	areas, err := services.GetWeakAreas(userSession.UserID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get weak areas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"weak_areas": areas,
+		"total":      len(areas),
 	})
 }
 
@@ -219,7 +424,8 @@ func GetUserStatsHandler(c *gin.Context) {
 
 	userSession := user.(*services.UserSession)
 	
-	stats, err := services.GetUserStats(userSession.UserID)
+	// 可按分类或分类树父节点筛选
+	stats, err := services.GetUserStatsInCategory(userSession.UserID, c.Query("category"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get user stats",
@@ -227,16 +433,38 @@ func GetUserStatsHandler(c *gin.Context) {
 		return
 	}
 
+	// 指定standard（标准ID）时附带各条款的掌握情况
+	if standardStr := c.Query("standard"); standardStr != "" {
+		standardID, err := strconv.ParseUint(standardStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid standard ID",
+			})
+			return
+		}
+		stats.ClauseStats, err = services.GetClauseStats(userSession.UserID, uint(standardID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Standard not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to get user stats",
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, stats)
 }
 
-// SearchQuestions 搜索题目
+// SearchQuestions 全文搜索题干、选项和解析，支持短语、OR、排除词，按相关度排序并返回高亮片段
+// 参数：keyword（搜索表达式）、limit（默认50，最多200）、cursor（上一页的next_cursor），筛选参数同题目列表
+// 结果附带命中总数及分类、题型的分面计数
 func SearchQuestions(c *gin.Context) {
-	keyword := c.Query("keyword")
-	category := c.Query("category")
-	qType := c.Query("type")
-	limitStr := c.Query("limit")
-	
+	keyword := strings.TrimSpace(c.Query("keyword"))
 	if keyword == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Search keyword is required",
@@ -244,79 +472,40 @@ func SearchQuestions(c *gin.Context) {
 		return
 	}
 
-	limit := 50 // 默认返回50道题
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
 	}
 
-	// 构建查询
-	query := services.DB.Where("question LIKE ?", "%"+keyword+"%")
-	
-	if category != "" {
-		query = query.Where("category = ?", category)
-	}
-	
-	if qType != "" {
-		query = query.Where("type = ?", qType)
+	filter, err := questionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	var questions []models.Question
-	if err := query.Limit(limit).Find(&questions).Error; err != nil {
+	page, err := services.SearchQuestions(keyword, filter, limit, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) || errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to search questions",
 		})
 		return
 	}
 
-	// 处理选项字段
-	for i := range questions {
-		if questions[i].Options != "" {
-			var options []string
-			if err := json.Unmarshal([]byte(questions[i].Options), &options); err == nil {
-				questions[i].Options = strings.Join(options, "|")
-			}
-		}
-	}
-
 	c.JSON(http.StatusOK, gin.H{
-		"questions": questions,
-		"total": len(questions),
-		"keyword": keyword,
+		"questions":   page.Questions,
+		"total":       page.Total,
+		"facets":      page.Facets,
+		"next_cursor": page.NextCursor,
+		"keyword":     keyword,
+		"limit":       limit,
+		"engine":      services.SearchEngine(),
 	})
 }
-
-// compareMultipleChoiceAnswer 比较多选题答案
-func compareMultipleChoiceAnswer(userAnswer, correctAnswer string) bool {
-	// 将答案按逗号分割并排序比较
-	userParts := strings.Split(userAnswer, ",")
-	correctParts := strings.Split(correctAnswer, ",")
-	
-	// 去除空格并转换为小写
-	for i := range userParts {
-		userParts[i] = strings.ToLower(strings.TrimSpace(userParts[i]))
-	}
-	for i := range correctParts {
-		correctParts[i] = strings.ToLower(strings.TrimSpace(correctParts[i]))
-	}
-	
-	// 检查长度是否相同
-	if len(userParts) != len(correctParts) {
-		return false
-	}
-	
-	// 检查每个选项是否都匹配
-	userMap := make(map[string]bool)
-	for _, part := range userParts {
-		userMap[part] = true
-	}
-	
-	for _, part := range correctParts {
-		if !userMap[part] {
-			return false
-		}
-	}
-	
-	return true
-}
\ No newline at end of file
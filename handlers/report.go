@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportQuestion 提交题目纠错报告
+func ReportQuestion(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	var req models.QuestionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := services.CreateQuestionReport(userSession.UserID, uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Question not found",
+			})
+		case errors.Is(err, services.ErrInvalidReportReason):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrDuplicateReport):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to create report",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetMyReports 获取当前用户提交的纠错报告
+func GetMyReports(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	reports, err := services.GetUserReports(userSession.UserID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get reports",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   len(reports),
+	})
+}
+
+// ListQuestionReports 获取纠错报告审核队列，默认只显示待处理的报告
+func ListQuestionReports(c *gin.Context) {
+	status := c.DefaultQuery("status", services.ReportStatusOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	var questionID uint64
+	if idStr := c.Query("question_id"); idStr != "" {
+		var err error
+		if questionID, err = strconv.ParseUint(idStr, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid question ID",
+			})
+			return
+		}
+	}
+
+	reports, total, err := services.ListQuestionReports(status, uint(questionID), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get reports",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   total,
+	})
+}
+
+// ResolveQuestionReport 处理纠错报告，可同时编辑题目
+func ResolveQuestionReport(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, ok := parseReportID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	reports, revision, err := services.ResolveQuestionReport(id, userSession.UserID, &req)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"reports":  reports,
+		"revision": revision,
+	}
+
+	// 编辑题目时按需立即重新判分
+	if revision != nil && req.Update.Regrade {
+		result, err := services.RegradeQuestion(revision.QuestionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Report resolved but regrade failed",
+			})
+			return
+		}
+		response["regrade"] = result
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RejectQuestionReport 驳回纠错报告
+func RejectQuestionReport(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, ok := parseReportID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := services.RejectQuestionReport(id, userSession.UserID, req.Note)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseReportID 解析路径中的报告ID，失败时已写入响应
+func parseReportID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid report ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondReportError 将处理报告时的错误转换为响应
+func respondReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Report not found",
+		})
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrNoQuestionChanges), errors.Is(err, services.ErrEmptyAnswer),
+		errors.Is(err, services.ErrInvalidDifficulty):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process report",
+		})
+	}
+}
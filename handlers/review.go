@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListPendingAnswers 人工复核队列，支持 status（默认pending，可选reviewed）、question_id、limit、offset
+func ListPendingAnswers(c *gin.Context) {
+	status := c.DefaultQuery("status", services.ReviewStatusPending)
+	if status != services.ReviewStatusPending && status != services.ReviewStatusReviewed {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid review status",
+		})
+		return
+	}
+
+	var questionID uint64
+	if s := c.Query("question_id"); s != "" {
+		var err error
+		if questionID, err = strconv.ParseUint(s, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid question ID",
+			})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	answers, total, err := services.ListPendingAnswers(status, uint(questionID), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get review queue",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"answers": answers,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// ReviewAnswer 人工复核答题记录
+func ReviewAnswer(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid answer ID",
+		})
+		return
+	}
+
+	var req models.AnswerReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	answer, err := services.ReviewAnswer(uint(id), userSession.UserID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Answer not found",
+			})
+		case errors.Is(err, services.ErrInvalidScore):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to review answer",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GetDueReviews 获取到期待复习的题目，支持 category、limit（默认20，最多100）
+func GetDueReviews(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	category := c.Query("category")
+
+	cards, questions, err := services.GetDueReviews(userSession.UserID, category, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get due reviews",
+		})
+		return
+	}
+	summary, err := services.GetReviewSummary(userSession.UserID, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get due reviews",
+		})
+		return
+	}
+
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get due reviews",
+		})
+		return
+	}
+	reviews := make([]models.DueReview, len(views))
+	for i := range views {
+		views[i].Options = displayOptions(views[i].Options)
+		reviews[i] = models.DueReview{QuestionView: views[i], Schedule: cards[i]}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": reviews,
+		"total":     len(reviews),
+		"summary":   summary,
+	})
+}
+
+// SubmitReviewAnswer 复习模式提交答案，判分后按自评回忆质量更新复习计划
+func SubmitReviewAnswer(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	var req models.ReviewAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+
+	question, err := services.Cache.GetQuestion(req.QuestionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Question not found",
+		})
+		return
+	}
+
+	userAnswer := strings.TrimSpace(req.Answer)
+	grade := services.GradeAnswer(question, userAnswer)
+
+	_, card, err := services.SaveReviewAnswer(userSession.UserID, question, userAnswer, grade, req.Quality)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuality) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save answer",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReviewAnswerResponse{
+		AnswerResponse: answerResponse(question, grade),
+		Schedule:       card,
+	})
+}
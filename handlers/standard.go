@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+	"quiz-system/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListStandards 获取标准目录
+func ListStandards(c *gin.Context) {
+	standards, err := services.ListStandards()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get standards",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"standards": standards,
+		"total":     len(standards),
+	})
+}
+
+// GetStandard 获取标准及其条款
+func GetStandard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid standard ID",
+		})
+		return
+	}
+
+	standard, err := services.GetStandard(uint(id))
+	if err != nil {
+		respondStandardError(c, err, "Failed to get standard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"standard": standard,
+	})
+}
+
+// GetClauseQuestions 按条款浏览题目
+func GetClauseQuestions(c *gin.Context) {
+	user, _ := c.Get("user")
+	userSession := user.(*services.UserSession)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid standard ID",
+		})
+		return
+	}
+	clauseID, err := strconv.ParseUint(c.Param("clauseId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid clause ID",
+		})
+		return
+	}
+
+	clause, questions, err := services.GetClauseQuestions(uint(id), uint(clauseID))
+	if err != nil {
+		respondStandardError(c, err, "Failed to get questions")
+		return
+	}
+
+	views, err := services.AttachPersonalData(userSession.UserID, questions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get questions",
+		})
+		return
+	}
+	for i := range views {
+		views[i].Options = displayOptions(views[i].Options)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clause":    clause,
+		"questions": views,
+		"total":     len(views),
+	})
+}
+
+// CreateStandard 创建标准（管理员）
+func CreateStandard(c *gin.Context) {
+	var req models.StandardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	standard, err := services.CreateStandard(&req)
+	if err != nil {
+		respondStandardError(c, err, "Failed to create standard")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"standard": standard,
+	})
+}
+
+// UpdateStandard 更新标准及其条款（管理员）
+func UpdateStandard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid standard ID",
+		})
+		return
+	}
+
+	var req models.StandardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	standard, err := services.UpdateStandard(uint(id), &req)
+	if err != nil {
+		respondStandardError(c, err, "Failed to update standard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"standard": standard,
+	})
+}
+
+// DeleteStandard 删除标准（管理员）
+func DeleteStandard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid standard ID",
+		})
+		return
+	}
+
+	if err := services.DeleteStandard(uint(id)); err != nil {
+		respondStandardError(c, err, "Failed to delete standard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Standard deleted",
+	})
+}
+
+// SetQuestionClauses 设置题目关联的标准条款（管理员）
+func SetQuestionClauses(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid question ID",
+		})
+		return
+	}
+
+	var req models.QuestionClauseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	clauses, err := services.SetQuestionClauses(uint(id), req.ClauseIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Question not found",
+			})
+		case errors.Is(err, services.ErrClauseNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save clauses",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": id,
+		"clauses":     clauses,
+	})
+}
+
+// respondStandardError 将标准目录相关错误转换为HTTP响应
+func respondStandardError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Standard not found",
+		})
+	case errors.Is(err, services.ErrClauseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrStandardExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrDuplicateClause):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fallback,
+		})
+	}
+}
+
+// ImportStandardText 上传标准原文（multipart字段file，Markdown或纯文本），按条款编号拆分后导入（管理员）
+// replace=true时删除原文中没有的条款
+func ImportStandardText(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid standard ID",
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "File is required",
+		})
+		return
+	}
+	if fileHeader.Size > services.MaxStandardTextSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Standard text file is too large",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxStandardTextSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+
+	result, err := services.ImportStandardText(uint(id), string(data), c.Query("replace") == "true")
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStandardText) || errors.Is(err, services.ErrNoClausesFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondStandardError(c, err, "Failed to import standard text")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": result,
+	})
+}
+
+// SearchClauses 全文搜索标准条款，参数：keyword（语法同题目搜索）、standard（标准ID，可选）、limit（默认20，最多100）
+func SearchClauses(c *gin.Context) {
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Search keyword is required",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var standardID uint64
+	if s := c.Query("standard"); s != "" {
+		var err error
+		if standardID, err = strconv.ParseUint(s, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid standard ID",
+			})
+			return
+		}
+	}
+
+	hits, err := services.SearchClauses(keyword, uint(standardID), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search clauses",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clauses": hits,
+		"total":   len(hits),
+		"keyword": keyword,
+		"engine":  services.SearchEngine(),
+	})
+}
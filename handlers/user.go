@@ -114,6 +114,7 @@ func Login(c *gin.Context) {
 	session := &services.UserSession{
 		UserID:    user.ID,
 		Username:  user.Username,
+		IsAdmin:   user.IsAdmin,
 		LoginTime: time.Now(),
 		LastSeen:  time.Now(),
 	}
@@ -127,6 +128,7 @@ func Login(c *gin.Context) {
 		"user": models.UserResponse{
 			ID:       user.ID,
 			Username: user.Username,
+			IsAdmin:  user.IsAdmin,
 		},
 		"session_id": sessionID,
 	})
@@ -178,6 +180,7 @@ func GetProfile(c *gin.Context) {
 		"user": models.UserResponse{
 			ID:       userSession.UserID,
 			Username: userSession.Username,
+			IsAdmin:  userSession.IsAdmin,
 		},
 		"stats": stats,
 	})
@@ -210,6 +213,30 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// AdminMiddleware 管理员权限中间件，需在AuthMiddleware之后使用
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		if !user.(*services.UserSession).IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Admin privileges required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // generateSessionID 生成会话ID
 func generateSessionID() (string, error) {
 	bytes := make([]byte, 32)
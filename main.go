@@ -4,6 +4,7 @@ import (
 	"embed"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"quiz-system/handlers"
@@ -15,6 +16,14 @@ import (
 var staticFiles embed.FS
 
 func main() {
+	// 命令行子命令（导入导出等）
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	// 初始化数据库
 	if err := services.InitDatabase(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -89,6 +98,9 @@ func main() {
 			{
 				user.GET("/profile", handlers.GetProfile)
 				user.GET("/stats", handlers.GetUserStatsHandler)
+				user.GET("/coverage", handlers.GetCoverage)
+				user.GET("/weak-areas", handlers.GetWeakAreas)
+				user.GET("/reports", handlers.GetMyReports)
 			}
 
 			// 学习进度相关路由
@@ -109,10 +121,83 @@ func main() {
 			{
 				questions.GET("/", handlers.GetQuestions)
 				questions.GET("/:id", handlers.GetQuestion)
+				questions.GET("/:id/changelog", handlers.GetQuestionChangelog)
 				questions.GET("/categories", handlers.GetCategories)
+				questions.GET("/categories/tree", handlers.GetCategoryTree)
+				questions.GET("/tags", handlers.GetTags)
+				questions.GET("/export", handlers.ExportQuestions)
 				questions.GET("/search", handlers.SearchQuestions)
 				questions.GET("/wrong", handlers.GetWrongQuestions)
+				questions.DELETE("/:id/wrong", handlers.RemoveWrongQuestion)
 				questions.POST("/submit", handlers.SubmitAnswer)
+				questions.POST("/:id/report", handlers.ReportQuestion)
+				questions.GET("/:id/explanations", handlers.GetQuestionExplanations)
+				questions.POST("/:id/explanations", handlers.SubmitExplanation)
+				questions.PUT("/:id/bookmark", handlers.AddBookmark)
+				questions.DELETE("/:id/bookmark", handlers.RemoveBookmark)
+				questions.PUT("/:id/note", handlers.SaveNote)
+				questions.DELETE("/:id/note", handlers.DeleteNote)
+			}
+
+			// 顺序练习路由
+			practice := authenticated.Group("/practice")
+			{
+				practice.GET("/sequential", handlers.GetSequentialPractice)
+				practice.GET("/sequential/progress", handlers.GetSequentialProgress)
+				practice.PUT("/sequential/position", handlers.SaveSequentialPosition)
+				practice.DELETE("/sequential/position", handlers.ResetSequentialPosition)
+				practice.POST("/custom", handlers.StartCustomPractice)
+				practice.POST("/custom/preview", handlers.PreviewCustomPractice)
+			}
+
+			// 间隔复习路由
+			review := authenticated.Group("/review")
+			{
+				review.GET("/due", handlers.GetDueReviews)
+				review.POST("/answer", handlers.SubmitReviewAnswer)
+			}
+
+			// 学习计划路由
+			plan := authenticated.Group("/plan")
+			{
+				plan.GET("/today", handlers.GetTodayPlan)
+				plan.GET("/settings", handlers.GetStudySettings)
+				plan.PUT("/settings", handlers.UpdateStudySettings)
+				plan.GET("/history", handlers.GetPlanHistory)
+			}
+
+			// 标准目录路由
+			standards := authenticated.Group("/standards")
+			{
+				standards.GET("/", handlers.ListStandards)
+				standards.GET("/search", handlers.SearchClauses)
+				standards.GET("/:id", handlers.GetStandard)
+				standards.GET("/:id/clauses/:clauseId/questions", handlers.GetClauseQuestions)
+			}
+
+			// 题目附件
+			authenticated.GET("/attachments/:sha256", handlers.GetAttachment)
+
+			// 收藏路由
+			bookmarks := authenticated.Group("/bookmarks")
+			{
+				bookmarks.GET("/", handlers.GetBookmarks)
+				bookmarks.POST("/practice", handlers.StartBookmarkPractice)
+			}
+
+			// 社区解析路由
+			explanations := authenticated.Group("/explanations")
+			{
+				explanations.POST("/:id/vote", handlers.VoteExplanation)
+				explanations.DELETE("/:id", handlers.DeleteExplanation)
+			}
+
+			// 站内通知路由
+			notifications := authenticated.Group("/notifications")
+			{
+				notifications.GET("/", handlers.GetNotifications)
+				notifications.PUT("/:id/read", handlers.MarkNotificationRead)
+				notifications.PUT("/read-all", handlers.MarkAllNotificationsRead)
 			}
 
 			// 考试相关路由
@@ -131,6 +216,33 @@ func main() {
 				exam.GET("/:examId/answer-sheet/stats", handlers.GetAnswerSheetStats)
 				exam.GET("/:examId/answer-sheet/question/:questionNum", handlers.JumpToQuestion)
 			}
+
+			// 题库管理路由（需要管理员权限）
+			admin := authenticated.Group("/admin")
+			admin.Use(handlers.AdminMiddleware())
+			{
+				admin.PUT("/questions/:id", handlers.UpdateQuestion)
+				admin.POST("/questions/:id/regrade", handlers.RegradeQuestion)
+				admin.POST("/regrade", handlers.StartRegradeJob)
+				admin.GET("/regrade", handlers.GetRegradeJobStatus)
+				admin.POST("/import", handlers.ImportQuestions)
+				admin.GET("/reports", handlers.ListQuestionReports)
+				admin.POST("/reports/:id/resolve", handlers.ResolveQuestionReport)
+				admin.POST("/reports/:id/reject", handlers.RejectQuestionReport)
+				admin.GET("/explanations", handlers.ListExplanationCandidates)
+				admin.POST("/explanations/:id/promote", handlers.PromoteExplanation)
+				admin.GET("/duplicates", handlers.GetDuplicateClusters)
+				admin.GET("/reviews", handlers.ListPendingAnswers)
+				admin.POST("/reviews/:id", handlers.ReviewAnswer)
+				admin.GET("/attachments", handlers.ListAttachments)
+				admin.POST("/attachments", handlers.UploadAttachment)
+				admin.DELETE("/attachments/:sha256", handlers.DeleteAttachment)
+				admin.POST("/standards", handlers.CreateStandard)
+				admin.PUT("/standards/:id", handlers.UpdateStandard)
+				admin.DELETE("/standards/:id", handlers.DeleteStandard)
+				admin.POST("/standards/:id/text", handlers.ImportStandardText)
+				admin.PUT("/questions/:id/clauses", handlers.SetQuestionClauses)
+			}
 		}
 
 		// 系统状态路由（无需认证）
@@ -6,13 +6,20 @@ import (
 
 // UserAnswer 用户答题记录
 type UserAnswer struct {
-	ID         uint      `json:"id" gorm:"primaryKey"`
-	UserID     uint      `json:"user_id" gorm:"not null;index"`
-	QuestionID uint      `json:"question_id" gorm:"not null;index"`
-	UserAnswer string    `json:"user_answer" gorm:"not null"`
-	IsCorrect  bool      `json:"is_correct" gorm:"not null"`
-	Category   string    `json:"category" gorm:"not null;index"`
-	AnsweredAt time.Time `json:"answered_at"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	QuestionID    uint       `json:"question_id" gorm:"not null;index"`
+	UserAnswer    string     `json:"user_answer" gorm:"not null"`
+	IsCorrect     bool       `json:"is_correct" gorm:"not null"`
+	Category      string     `json:"category" gorm:"not null;index"`
+	Revision      int        `json:"revision"`                                                 // 判分时所依据的题目版本
+	Score         float64    `json:"score" gorm:"not null;default:0"`                          // 得分比例0-1，填空题和简答题可得部分分
+	ReviewStatus  string     `json:"review_status,omitempty" gorm:"not null;default:'';index"` // pending 等待人工复核，reviewed 已人工复核
+	ReviewerID    uint       `json:"reviewer_id,omitempty"`
+	ReviewComment string     `json:"review_comment,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	ExamRecordID  uint       `json:"exam_record_id,omitempty" gorm:"index"` // 考试中作答时对应的考试记录
+	AnsweredAt    time.Time  `json:"answered_at"`
 }
 
 // ExamRecord 考试记录
@@ -32,13 +39,26 @@ type ExamRecord struct {
 type AnswerRequest struct {
 	QuestionID uint   `json:"question_id" binding:"required"`
 	Answer     string `json:"answer" binding:"required"`
+	// SequentialCategory 顺序练习时的分类，作答后该分类的练习位置移到本题
+	SequentialCategory string `json:"sequential_category,omitempty"`
+}
+
+// GradeResult 判分结果
+type GradeResult struct {
+	IsCorrect       bool     `json:"is_correct"`
+	Score           float64  `json:"score"`                   // 得分比例0-1
+	ReviewStatus    string   `json:"review_status,omitempty"` // pending 表示等待人工复核，复核前按未答对统计
+	MatchedKeywords []string `json:"matched_keywords,omitempty"`
 }
 
 // AnswerResponse 答题响应
 type AnswerResponse struct {
-	IsCorrect   bool   `json:"is_correct"`
+	GradeResult
 	CorrectAnswer string `json:"correct_answer"`
 	Explanation string `json:"explanation,omitempty"`
+	ExplanationSource string `json:"explanation_source,omitempty"` // official 或 community
+	CommunityExplanationID uint `json:"community_explanation_id,omitempty"`
+	Clauses []ClauseRef `json:"clauses,omitempty"` // 关联的标准条款及原文摘录
 }
 
 // UserStats 用户统计
@@ -48,24 +68,50 @@ type UserStats struct {
 	Accuracy      float64          `json:"accuracy"`
 	CategoryStats []CategoryStats  `json:"category_stats"`
 	WrongQuestions []WrongQuestion `json:"wrong_questions"`
+	PendingReview int              `json:"pending_review"` // 等待人工复核的答题数，不计入上面的统计
+	ClauseStats   []ClauseStats    `json:"clause_stats,omitempty"` // 按standard参数统计的各条款掌握情况
+	Coverage      *Coverage        `json:"coverage,omitempty"`     // 题库覆盖情况
+}
+
+// CoverageStats 题库覆盖情况，Seen为作答过的题目数（含等待人工复核的），Coverage为其占题目数的百分比
+type CoverageStats struct {
+	Name     string  `json:"name,omitempty"` // 分类或题型
+	Total    int     `json:"total"`
+	Seen     int     `json:"seen"`
+	Unseen   int     `json:"unseen"`
+	Coverage float64 `json:"coverage"`
+}
+
+// Coverage 用户对题库的总体覆盖情况及按分类、题型的覆盖情况
+type Coverage struct {
+	CoverageStats
+	Categories []CoverageStats `json:"categories"`
+	Types      []CoverageStats `json:"types"`
 }
 
-// CategoryStats 分类统计
+// CategoryStats 分类统计，Accuracy为累计正确率，Mastery为按近期表现估计的掌握程度
 type CategoryStats struct {
 	Category     string  `json:"category"`
 	Total        int     `json:"total"`
 	Correct      int     `json:"correct"`
 	Accuracy     float64 `json:"accuracy"`
+	Mastery      float64 `json:"mastery"`  // 掌握程度百分比
+	Mastered     bool    `json:"mastered"` // 掌握程度达到95%
 }
 
-// WrongQuestion 错题信息
+// WrongQuestion 错题信息，UserAnswer为最近一次的错误答案
 type WrongQuestion struct {
 	QuestionID  uint   `json:"question_id"`
 	Question    string `json:"question"`
 	UserAnswer  string `json:"user_answer"`
 	CorrectAnswer string `json:"correct_answer"`
 	Category    string `json:"category"`
-	AnsweredAt  time.Time `json:"answered_at"`
+	Status        string     `json:"status"`
+	WrongCount    int        `json:"wrong_count"`
+	CorrectStreak int        `json:"correct_streak"`
+	FirstWrongAt  time.Time  `json:"first_wrong_at"`
+	LastWrongAt   time.Time  `json:"last_wrong_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
 }
 
 // ExamSession 考试会话
@@ -77,4 +123,68 @@ type ExamSession struct {
 	StartTime   time.Time `json:"start_time"`
 	Duration    int       `json:"duration"`    // 考试时长（分钟）
 	IsCompleted bool      `json:"is_completed"`
-}
\ No newline at end of file
+	RecordID    uint      `json:"record_id"` // 对应的考试记录
+}
+// BlueprintSection 组卷规则中的一部分，按筛选条件随机抽取Count道题
+type BlueprintSection struct {
+	Type          string   `json:"type,omitempty"` // 为空时不限题型
+	Count         int      `json:"count"`
+	Category      string   `json:"category,omitempty"`
+	Tags          []string `json:"tags,omitempty"` // 需同时包含的标签
+	MinDifficulty int      `json:"min_difficulty,omitempty"`
+	MaxDifficulty int      `json:"max_difficulty,omitempty"`
+}
+
+// ExamBlueprint 组卷规则
+type ExamBlueprint struct {
+	Name     string             `json:"name"`
+	Duration int                `json:"duration"` // 考试时长（分钟），0为不限时
+	Sections []BlueprintSection `json:"sections"`
+	// DistinctClusters 同一近似重复簇的题目在一份试卷中至多出现一道
+	DistinctClusters bool `json:"distinct_clusters,omitempty"`
+}
+
+// BlueprintShortfall 组卷规则中符合条件的题目不足的部分
+type BlueprintShortfall struct {
+	Section   int `json:"section"` // 部分序号，从1开始
+	Requested int `json:"requested"`
+	Selected  int `json:"selected"`
+}
+
+// ExamStartRequest 开始考试请求，提供Blueprint时按自定义规则组卷，WrongQuestions为错题组卷参数
+type ExamStartRequest struct {
+	Blueprint      *ExamBlueprint    `json:"blueprint"`
+	WrongQuestions *WrongExamOptions `json:"wrong_questions"`
+}
+
+// WrongExamOptions 错题组卷参数，按答错次数和最近答错时间加权随机抽题
+type WrongExamOptions struct {
+	Types           []string `json:"types,omitempty"`    // 为空时不限题型
+	Count           int      `json:"count,omitempty"`    // 题目数，默认50
+	Duration        *int     `json:"duration,omitempty"` // 考试时长（分钟），默认每题1分钟，0为不限时
+	IncludeResolved bool     `json:"include_resolved"`   // 同时抽取已连续答对移出错题本的题目
+}
+
+// ExamReviewItem 考试结束后的逐题回顾
+type ExamReviewItem struct {
+	QuestionView
+	UserAnswer   string      `json:"user_answer"`
+	IsCorrect    bool        `json:"is_correct"`
+	Score        float64     `json:"score"`
+	ReviewStatus string      `json:"review_status,omitempty"`
+	Clauses      []ClauseRef `json:"clauses,omitempty"` // 关联的标准条款及原文摘录
+}
+
+// PendingAnswer 人工复核队列中的答题记录
+type PendingAnswer struct {
+	UserAnswer
+	Username string    `json:"username"`
+	Question *Question `json:"question,omitempty"`
+}
+
+// AnswerReviewRequest 人工复核请求，未提供得分时答对记1分、答错记0分
+type AnswerReviewRequest struct {
+	IsCorrect *bool    `json:"is_correct" binding:"required"`
+	Score     *float64 `json:"score"`
+	Comment   string   `json:"comment"`
+}
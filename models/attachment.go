@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment 题目附件（图片等），内容存储在数据库中，按SHA-256去重
+// 题干、选项和解析中以 ![说明](/api/attachments/<sha256>) 引用图片，以 [文件名](/api/attachments/<sha256>) 引用其他附件
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	SHA256      string    `json:"sha256" gorm:"size:64;not null;uniqueIndex"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type" gorm:"not null"`
+	Size        int64     `json:"size"`
+	Data        []byte    `json:"-" gorm:"not null"`
+	UploaderID  uint      `json:"uploader_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentInfo 附件信息及引用它的题目
+type AttachmentInfo struct {
+	Attachment
+	Reference   string `json:"reference" gorm:"-"`
+	QuestionIDs []uint `json:"question_ids" gorm:"-"`
+}
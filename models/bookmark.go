@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Bookmark 用户收藏的题目
+type Bookmark struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_bookmark"`
+	QuestionID uint      `json:"question_id" gorm:"not null;uniqueIndex:idx_user_bookmark"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QuestionNote 用户对题目的个人笔记，每人每题一条
+type QuestionNote struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_note"`
+	QuestionID uint      `json:"question_id" gorm:"not null;uniqueIndex:idx_user_note"`
+	Content    string    `json:"content" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NoteRequest 保存笔记请求
+type NoteRequest struct {
+	Content string `json:"content"`
+}
+
+// QuestionView 附带当前用户书签和笔记的题目
+// 内嵌题目的副本，修改时不会影响缓存中的题目
+type QuestionView struct {
+	Question
+	Bookmarked bool   `json:"bookmarked"`
+	Note       string `json:"note,omitempty"`
+}
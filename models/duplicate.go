@@ -0,0 +1,28 @@
+package models
+
+// DuplicateMember 近似重复簇中的题目
+type DuplicateMember struct {
+	ID       uint   `json:"id"`
+	Type     string `json:"type"`
+	Question string `json:"question"`
+	Options  string `json:"options"`
+	Answer   string `json:"answer"`
+	Category string `json:"category"`
+}
+
+// DuplicatePair 簇内相似度达到阈值的一对题目
+type DuplicatePair struct {
+	QuestionID      uint    `json:"question_id"`
+	OtherQuestionID uint    `json:"other_question_id"`
+	Similarity      float64 `json:"similarity"`
+}
+
+// DuplicateCluster 近似重复题目簇，簇内题目通过相似题目对相连
+type DuplicateCluster struct {
+	ID            int               `json:"id"`
+	Size          int               `json:"size"`
+	MaxSimilarity float64           `json:"max_similarity"`
+	MinSimilarity float64           `json:"min_similarity"`
+	Questions     []DuplicateMember `json:"questions"`
+	Pairs         []DuplicatePair   `json:"pairs"`
+}
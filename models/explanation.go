@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// CommunityExplanation 用户提交的题目解析
+type CommunityExplanation struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	QuestionID uint      `json:"question_id" gorm:"not null;index"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Content    string    `json:"content" gorm:"not null"`
+	Upvotes    int       `json:"upvotes" gorm:"not null;default:0"`
+	Downvotes  int       `json:"downvotes" gorm:"not null;default:0"`
+	Score      int       `json:"score" gorm:"not null;default:0;index"`   // 赞成票减反对票
+	Status     string    `json:"status" gorm:"not null;default:'visible'"` // visible, promoted, hidden
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ExplanationVote 用户对社区解析的投票，每人每条解析一票
+type ExplanationVote struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExplanationID uint      `json:"explanation_id" gorm:"not null;uniqueIndex:idx_explanation_vote"`
+	UserID        uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_explanation_vote"`
+	Value         int       `json:"value" gorm:"not null"` // 1 或 -1
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CommunityExplanationView 带作者和当前用户投票的社区解析
+type CommunityExplanationView struct {
+	CommunityExplanation
+	Username string `json:"username"`
+	MyVote   int    `json:"my_vote"`
+}
+
+// ExplanationRequest 提交社区解析请求
+type ExplanationRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ExplanationVoteRequest 投票请求，value为1赞成、-1反对、0撤销
+type ExplanationVoteRequest struct {
+	Value int `json:"value"`
+}
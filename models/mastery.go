@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// CategoryMastery 用户对分类的掌握程度，按贝叶斯知识追踪（BKT）在每次作答后更新
+type CategoryMastery struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_mastery"`
+	Category       string    `json:"category" gorm:"not null;uniqueIndex:idx_user_mastery"`
+	PKnown         float64   `json:"p_known"`  // 已掌握的后验概率0-1
+	Attempts       int       `json:"attempts"` // 计入模型的作答次数
+	Correct        int       `json:"correct"`
+	LastAnsweredAt time.Time `json:"last_answered_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WeakArea 薄弱分类，按掌握程度从低到高排列
+type WeakArea struct {
+	Category   string    `json:"category"`
+	Mastery    float64   `json:"mastery"` // 掌握程度百分比
+	Attempts   int       `json:"attempts"`
+	Accuracy   float64   `json:"accuracy"` // 累计正确率百分比
+	Unseen     int       `json:"unseen"`   // 该分类中未作答过的题目数
+	LastAnswer time.Time `json:"last_answered_at"`
+}
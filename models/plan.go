@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// StudySettings 用户的每日学习设置
+type StudySettings struct {
+	ID             uint       `json:"-" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	DailyQuestions int        `json:"daily_questions"`     // 每日题量，0为不限（同时未设置每日时间时按30题）
+	DailyMinutes   int        `json:"daily_minutes"`       // 每日学习时间（分钟），按每题1分钟折算题量，0为不限
+	ExamDate       *time.Time `json:"exam_date,omitempty"` // 考试日期，设置后按剩余天数安排新题进度
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// StudySettingsRequest 更新每日学习设置请求，未提供的字段保持不变；exam_date为空字符串时清除考试日期
+type StudySettingsRequest struct {
+	DailyQuestions *int    `json:"daily_questions"`
+	DailyMinutes   *int    `json:"daily_minutes"`
+	ExamDate       *string `json:"exam_date"` // 格式 2006-01-02
+}
+
+// StudyPlan 用户某一天的学习计划，当天首次获取时生成
+type StudyPlan struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	UserID        uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_user_plan_date"`
+	Date          string          `json:"date" gorm:"not null;uniqueIndex:idx_user_plan_date"` // 格式 2006-01-02
+	Budget        int             `json:"budget"`                                             // 当天的题量预算
+	DaysUntilExam *int            `json:"days_until_exam,omitempty"`
+	Items         []StudyPlanItem `json:"items,omitempty" gorm:"foreignKey:PlanID"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// StudyPlanItem 学习计划中的一道题，在任意模式下作答后即完成
+type StudyPlanItem struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	PlanID      uint       `json:"plan_id" gorm:"not null;index"`
+	QuestionID  uint       `json:"question_id" gorm:"not null;index"`
+	Kind        string     `json:"kind" gorm:"not null"` // review（到期复习）、weak（薄弱分类强化）、new（新题）
+	Category    string     `json:"category"`
+	SortOrder   int        `json:"sort_order"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// PlanQuestion 学习计划中的题目
+type PlanQuestion struct {
+	QuestionView
+	PlanKind string `json:"plan_kind"`
+	Done     bool   `json:"done"`
+}
+
+// PlanSection 学习计划中一类题目的完成情况
+type PlanSection struct {
+	Kind      string `json:"kind"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+}
+
+// PlanDay 某一天学习计划的完成情况
+type PlanDay struct {
+	Date       string  `json:"date"`
+	Total      int     `json:"total"`
+	Completed  int     `json:"completed"`
+	Completion float64 `json:"completion"` // 完成百分比
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// PracticePosition 用户在分类顺序练习中的位置，题目按ID顺序排列，记录最后作答的题目
+type PracticePosition struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_practice_category"`
+	Category       string    `json:"category" gorm:"not null;uniqueIndex:idx_user_practice_category"`
+	LastQuestionID uint      `json:"last_question_id"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SequentialProgress 分类顺序练习进度，Completed为排在当前位置及之前的题目数
+type SequentialProgress struct {
+	Category       string     `json:"category"`
+	LastQuestionID uint       `json:"last_question_id"`
+	Completed      int64      `json:"completed"`
+	Total          int64      `json:"total"`
+	Finished       bool       `json:"finished"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// PracticePositionRequest 保存顺序练习位置请求
+type PracticePositionRequest struct {
+	Category   string `json:"category" binding:"required"`
+	QuestionID uint   `json:"question_id" binding:"required"`
+}
+
+// PracticeSetRequest 自定义练习请求，各筛选条件同时生效，按条件随机抽取Count道题创建练习会话
+type PracticeSetRequest struct {
+	Categories        []string `json:"categories,omitempty"` // 属于其中任一分类（含分类树子节点）
+	Types             []string `json:"types,omitempty"`      // 为空时不限题型
+	Tags              []string `json:"tags,omitempty"`       // 需同时包含的标签
+	Difficulty        string   `json:"difficulty,omitempty"` // 如 3 或 2-4
+	OnlyUnseen        bool     `json:"only_unseen"`          // 只选从未作答过的题目
+	OnlyWrong         bool     `json:"only_wrong"`           // 只选错题本中的题目
+	OnlyBookmarked    bool     `json:"only_bookmarked"`      // 只选收藏的题目
+	ExcludeRecentDays int      `json:"exclude_recent_days"`  // 排除最近N天内作答过的题目，0为不排除
+	Count             int      `json:"count"`                // 题目数，默认20
+	Duration          int      `json:"duration"`             // 时间限制（分钟），0为不限时
+	AllowFewer        bool     `json:"allow_fewer"`          // 符合条件的题目不足Count道时按实际数量组卷
+}
+
+// PracticeSetPreview 自定义练习的可选题目数
+type PracticeSetPreview struct {
+	Available int64 `json:"available"` // 符合筛选条件的题目数
+	Count     int   `json:"count"`     // 将抽取的题目数
+	Enough    bool  `json:"enough"`
+}
@@ -7,13 +7,70 @@ import (
 // Question 题目模型
 type Question struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	Type        string    `json:"type" gorm:"not null"`        // single, multiple, judge
+	Type        string    `json:"type" gorm:"not null"`        // single, multiple, judge, fill, short
 	Question    string    `json:"question" gorm:"not null"`
 	Options     string    `json:"options,omitempty"`           // JSON格式存储选项
 	Answer      string    `json:"answer" gorm:"not null"`
 	Category    string    `json:"category" gorm:"not null"`
 	Explanation string    `json:"explanation,omitempty"`
+	Tags        []string  `json:"tags,omitempty" gorm:"serializer:json"`          // 标签，同步写入question_tags用于筛选
+	Difficulty  int       `json:"difficulty,omitempty" gorm:"not null;default:0"` // 难度1-5，0表示未设置
+	Grading     *GradingRule `json:"grading,omitempty" gorm:"serializer:json"`    // 填空题和简答题的判分规则
+	Revision    int       `json:"revision" gorm:"not null;default:1"`             // 当前版本号，每次编辑递增
 	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// QuestionTag 题目标签索引
+type QuestionTag struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	QuestionID uint   `json:"question_id" gorm:"not null;uniqueIndex:idx_question_tag"`
+	Tag        string `json:"tag" gorm:"not null;uniqueIndex:idx_question_tag;index"`
+}
+
+// QuestionRevision 题目版本快照
+type QuestionRevision struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	QuestionID    uint      `json:"question_id" gorm:"not null;uniqueIndex:idx_question_revision"`
+	Revision      int       `json:"revision" gorm:"not null;uniqueIndex:idx_question_revision"`
+	Type          string    `json:"type" gorm:"not null"`
+	Question      string    `json:"question" gorm:"not null"`
+	Options       string    `json:"options,omitempty"`
+	Answer        string    `json:"answer" gorm:"not null"`
+	Category      string    `json:"category" gorm:"not null"`
+	Explanation   string    `json:"explanation,omitempty"`
+	Tags          []string  `json:"tags,omitempty" gorm:"serializer:json"`
+	Difficulty    int       `json:"difficulty,omitempty"`
+	Grading       *GradingRule `json:"grading,omitempty" gorm:"serializer:json"`
+	ChangedFields string    `json:"changed_fields,omitempty"` // 逗号分隔的变更字段
+	ChangeNote    string    `json:"change_note,omitempty"`
+	EditorID      uint      `json:"editor_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// QuestionUpdateRequest 题目编辑请求，未提供的字段保持不变
+type QuestionUpdateRequest struct {
+	Type        *string           `json:"type"`
+	Question    *string           `json:"question"`
+	Options     map[string]string `json:"options"`
+	Answer      *string           `json:"answer"`
+	Category    *string           `json:"category"`
+	Explanation *string           `json:"explanation"`
+	Tags        []string          `json:"tags"` // 为null时不修改，[]表示清空
+	Difficulty  *int              `json:"difficulty"`
+	Grading     *GradingRule      `json:"grading"`
+	ChangeNote  string            `json:"change_note"`
+	Regrade     bool              `json:"regrade"` // 编辑后立即按新答案重新判分历史记录
+}
+
+// GradingRule 填空题和简答题的判分规则
+// 填空题的可接受答案写在Answer中：各空以";"分隔，同一空的多个可接受答案以"|"分隔，如 "SM3|SM3算法;256"
+// 简答题的Answer为参考答案，按关键词自动评分，无法自动判定时进入人工复核
+type GradingRule struct {
+	Normalize []string `json:"normalize,omitempty"`  // 填空题比较前的归一化规则：width、case、space、punct，为空时全部启用，none表示不归一化
+	Unordered bool     `json:"unordered,omitempty"`  // 填空题各空答案不限顺序
+	Keywords  []string `json:"keywords,omitempty"`   // 简答题得分关键词，同义词以"|"分隔
+	PassScore float64  `json:"pass_score,omitempty"` // 简答题关键词得分比例达到该值时自动判为正确，默认0.8
 }
 
 // QuestionJSON 用于解析questions.json的结构
@@ -32,8 +89,24 @@ type Category struct {
 	Count int    `json:"count"`
 }
 
+// CategoryNode 分类树节点
+// 叶子节点对应题目中实际的分类，Path即分类名；父节点的Count为所有子分类题目数之和
+type CategoryNode struct {
+	Name     string          `json:"name"`
+	Path     string          `json:"path"`
+	Count    int             `json:"count"`
+	Leaf     bool            `json:"leaf"`
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// Tag 标签统计
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
 // QuestionStats 题目统计
 type QuestionStats struct {
 	Total      int        `json:"total"`
 	Categories []Category `json:"categories"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// QuestionReport 用户提交的题目纠错报告
+type QuestionReport struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	QuestionID       uint       `json:"question_id" gorm:"not null;index"`
+	UserID           uint       `json:"user_id" gorm:"not null;index"`
+	Reason           string     `json:"reason" gorm:"not null"` // wrong_answer, wrong_question, typo, outdated, other
+	SuggestedAnswer  string     `json:"suggested_answer,omitempty"`
+	Comment          string     `json:"comment,omitempty"`
+	Revision         int        `json:"revision"`                              // 报告时的题目版本
+	Status           string     `json:"status" gorm:"not null;default:'open'"` // open, resolved, rejected
+	ResolutionNote   string     `json:"resolution_note,omitempty"`
+	ResolvedBy       uint       `json:"resolved_by,omitempty"`
+	ResolvedRevision int        `json:"resolved_revision,omitempty"` // 处理后的题目版本
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+}
+
+// QuestionReportRequest 提交纠错报告请求
+type QuestionReportRequest struct {
+	Reason          string `json:"reason" binding:"required"`
+	SuggestedAnswer string `json:"suggested_answer"`
+	Comment         string `json:"comment"`
+}
+
+// QuestionReportDetail 管理后台中的报告，附带题目当前内容
+type QuestionReportDetail struct {
+	QuestionReport
+	Question *Question `json:"question,omitempty"`
+}
+
+// ReportResolveRequest 处理纠错报告请求
+type ReportResolveRequest struct {
+	Note   string                 `json:"note"`
+	Update *QuestionUpdateRequest `json:"update"` // 同时编辑题目，编辑后该题所有待处理报告一并解决
+}
+
+// Notification 站内通知
+type Notification struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Type       string    `json:"type" gorm:"not null"` // report_resolved, report_rejected, explanation_promoted
+	Title      string    `json:"title" gorm:"not null"`
+	Message    string    `json:"message"`
+	QuestionID uint      `json:"question_id,omitempty"`
+	IsRead     bool      `json:"is_read" gorm:"default:false"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// ReviewCard 用户对单道题的记忆状态，按SM-2算法安排下次复习时间
+type ReviewCard struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_card;index:idx_user_card_due,priority:1"`
+	QuestionID     uint      `json:"question_id" gorm:"not null;uniqueIndex:idx_user_card"`
+	Category       string    `json:"category" gorm:"not null;default:''"`
+	EaseFactor     float64   `json:"ease_factor" gorm:"not null;default:2.5"` // 难易度因子，不低于1.3
+	Interval       int       `json:"interval"`                                 // 当前复习间隔（天）
+	Repetitions    int       `json:"repetitions"`                              // 连续答对次数，答错时清零
+	Lapses         int       `json:"lapses"`                                   // 累计遗忘次数
+	LastQuality    int       `json:"last_quality"`                             // 最近一次回忆质量0-5，3及以上为记住
+	DueAt          time.Time `json:"due_at" gorm:"not null;index:idx_user_card_due,priority:2"`
+	LastReviewedAt time.Time `json:"last_reviewed_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DueReview 到期待复习的题目
+type DueReview struct {
+	QuestionView
+	Schedule ReviewCard `json:"schedule"`
+}
+
+// ReviewSummary 复习队列概况
+type ReviewSummary struct {
+	Due      int64 `json:"due"`       // 当前已到期
+	DueToday int64 `json:"due_today"` // 今天结束前到期（含已到期）
+	Total    int64 `json:"total"`     // 已进入复习计划的题目数
+}
+
+// ReviewAnswerRequest 复习模式答题请求
+// Quality为自评回忆质量：答对时可选3（困难）、4（一般）、5（轻松），未提供时按判分结果推断；答错时不超过2
+type ReviewAnswerRequest struct {
+	QuestionID uint   `json:"question_id" binding:"required"`
+	Answer     string `json:"answer" binding:"required"`
+	Quality    *int   `json:"quality"`
+}
+
+// ReviewAnswerResponse 复习模式答题响应，附带更新后的复习计划
+type ReviewAnswerResponse struct {
+	AnswerResponse
+	Schedule *ReviewCard `json:"schedule,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// SearchHit 全文搜索结果，Snippets为命中字段的高亮片段（命中词以<mark>标记，其余文本已做HTML转义）
+type SearchHit struct {
+	Question
+	Rank     float64           `json:"rank" gorm:"column:rank"`
+	Snippets map[string]string `json:"snippets,omitempty" gorm:"-"`
+}
+
+// SearchFacet 分面计数
+type SearchFacet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// SearchPage 一页搜索结果，NextCursor为空表示没有下一页
+type SearchPage struct {
+	Questions  []SearchHit              `json:"questions"`
+	Total      int64                    `json:"total"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	Facets     map[string][]SearchFacet `json:"facets"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+)
+
+// Standard 标准目录条目，如 GB/T 39786-2021
+type Standard struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	Code      string           `json:"code" gorm:"not null;uniqueIndex"` // 标准号，如 GB/T 39786
+	Title     string           `json:"title" gorm:"not null"`
+	Version   string           `json:"version,omitempty"`  // 版本年份，如 2021
+	Category  string           `json:"category,omitempty"` // 对应的题目分类
+	Clauses   []StandardClause `json:"clauses,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// StandardClause 标准条款，Number为条款编号，如 5.2.1
+type StandardClause struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	StandardID    uint   `json:"standard_id" gorm:"not null;uniqueIndex:idx_standard_clause"`
+	Number        string `json:"number" gorm:"not null;uniqueIndex:idx_standard_clause"`
+	Title         string `json:"title,omitempty"`
+	Content       string `json:"content,omitempty"` // 条款原文，标准目录列表中不返回
+	SortOrder     int    `json:"sort_order"`
+	QuestionCount int64  `json:"question_count" gorm:"-"`
+}
+
+// QuestionClause 题目与标准条款的关联，一道题可关联多个条款
+type QuestionClause struct {
+	ID         uint `json:"id" gorm:"primaryKey"`
+	QuestionID uint `json:"question_id" gorm:"not null;uniqueIndex:idx_question_clause"`
+	ClauseID   uint `json:"clause_id" gorm:"not null;uniqueIndex:idx_question_clause;index"`
+}
+
+// StandardSummary 标准目录列表项
+type StandardSummary struct {
+	Standard
+	ClauseCount   int64 `json:"clause_count"`
+	QuestionCount int64 `json:"question_count"` // 关联到条款的题目数
+}
+
+// StandardRequest 创建或更新标准请求，更新时按条款编号合并：保留已有条款，新增缺少的，删除未列出的
+type StandardRequest struct {
+	Code     string          `json:"code" binding:"required"`
+	Title    string          `json:"title" binding:"required"`
+	Version  string          `json:"version"`
+	Category string          `json:"category"`
+	Clauses  []ClauseRequest `json:"clauses"`
+}
+
+// ClauseRequest 条款定义，Content为空时保留条款已有的原文
+type ClauseRequest struct {
+	Number  string `json:"number" binding:"required"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// StandardTextImportResult 标准原文导入结果
+type StandardTextImportResult struct {
+	Clauses int `json:"clauses"` // 原文中解析出的条款数
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"` // replace为true时删除的未出现在原文中的条款
+}
+
+// QuestionClauseRequest 设置题目关联条款请求，为空时清除关联
+type QuestionClauseRequest struct {
+	ClauseIDs []uint `json:"clause_ids"`
+}
+
+// ClauseRef 题目关联的条款，Excerpt为条款原文摘录
+type ClauseRef struct {
+	ClauseID     uint   `json:"clause_id"`
+	StandardID   uint   `json:"standard_id"`
+	StandardCode string `json:"standard_code"`
+	Number       string `json:"number"`
+	Title        string `json:"title,omitempty"`
+	Excerpt      string `json:"excerpt,omitempty"`
+}
+
+// ClauseSearchHit 条款搜索结果，Snippet为命中处的高亮片段（命中词以<mark>标记，其余文本已做HTML转义）
+type ClauseSearchHit struct {
+	ClauseRef
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// ClauseStats 条款掌握情况
+// Mastered为最近一次作答正确的题目数，Mastery为其占条款题目数的百分比
+type ClauseStats struct {
+	ClauseID      uint    `json:"clause_id"`
+	Number        string  `json:"number"`
+	Title         string  `json:"title,omitempty"`
+	QuestionCount int     `json:"question_count"`
+	Answered      int     `json:"answered"` // 作答过的题目数
+	Total         int     `json:"total"`    // 作答次数
+	Correct       int     `json:"correct"`
+	Accuracy      float64 `json:"accuracy"`
+	Mastered      int     `json:"mastered"`
+	Mastery       float64 `json:"mastery"`
+}
@@ -13,6 +13,7 @@ type User struct {
 	CreatedAt     time.Time `json:"created_at"`
 	LoginAttempts int       `json:"-" gorm:"default:0"`
 	LockedUntil   *time.Time `json:"-"`
+	IsAdmin       bool      `json:"is_admin" gorm:"default:false"` // 题库管理员
 }
 
 // UserRegister 用户注册请求
@@ -31,6 +32,7 @@ type UserLogin struct {
 type UserResponse struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
+	IsAdmin  bool   `json:"is_admin,omitempty"`
 }
 
 // SetPassword 设置密码（加密存储）
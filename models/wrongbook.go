@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// WrongBookEntry 错题本条目，每人每题一条；连续答对达到次数后自动移出，再次答错时重新加入
+type WrongBookEntry struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_user_wrong;index:idx_user_wrong_status,priority:1"`
+	QuestionID    uint       `json:"question_id" gorm:"not null;uniqueIndex:idx_user_wrong"`
+	Category      string     `json:"category" gorm:"not null;default:''"`
+	Status        string     `json:"status" gorm:"not null;default:'active';index:idx_user_wrong_status,priority:2"` // active, graduated, removed
+	WrongCount    int        `json:"wrong_count"`    // 累计答错次数
+	CorrectStreak int        `json:"correct_streak"` // 最近一次答错后连续答对的次数
+	LastAnswer    string     `json:"last_answer"`    // 最近一次的错误答案
+	FirstWrongAt  time.Time  `json:"first_wrong_at"`
+	LastWrongAt   time.Time  `json:"last_wrong_at" gorm:"index"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"` // 移出错题本的时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+)
+
+// aikenDefaultCategory Aiken格式不含分类，导入时使用的默认分类
+const aikenDefaultCategory = "Aiken导入"
+
+// aikenOptionPattern 选项行，如 "A. xxx" 或 "A) xxx"
+var aikenOptionPattern = regexp.MustCompile(`^([A-Z])[.)．、]\s*(.*)$`)
+
+// aikenAnswerPattern 答案行，如 "ANSWER: A"；兼容多个字母的多选写法
+var aikenAnswerPattern = regexp.MustCompile(`^ANSWER\s*[:：]\s*([A-Z][A-Z,，\s]*)$`)
+
+// parseAiken 解析Aiken格式：题干、选项行、ANSWER行，题目之间以空行分隔
+func parseAiken(r io.Reader) ([]QuestionData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var questions []QuestionData
+	var stem []string
+	options := make(map[string]string)
+	lastOption := ""
+	lineNo := 0
+
+	reset := func() {
+		stem = nil
+		options = make(map[string]string)
+		lastOption = ""
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		if m := aikenAnswerPattern.FindStringSubmatch(line); m != nil {
+			if len(stem) == 0 || len(options) < 2 {
+				return nil, fmt.Errorf("line %d: answer without question or options", lineNo)
+			}
+			qd, err := buildAikenQuestion(strings.Join(stem, "\n"), options, m[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			questions = append(questions, *qd)
+			reset()
+			continue
+		}
+
+		if m := aikenOptionPattern.FindStringSubmatch(line); m != nil && len(stem) > 0 {
+			options[m[1]] = strings.TrimSpace(m[2])
+			lastOption = m[1]
+			continue
+		}
+
+		if lastOption != "" {
+			// 选项开始后的非选项行视为上一选项的续行
+			options[lastOption] += "\n" + line
+			continue
+		}
+		stem = append(stem, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(stem) > 0 {
+		return nil, fmt.Errorf("line %d: question is missing an ANSWER line", lineNo)
+	}
+
+	return questions, nil
+}
+
+// buildAikenQuestion 根据选项和答案字母确定题型
+func buildAikenQuestion(stem string, options map[string]string, answer string) (*QuestionData, error) {
+	var letters []string
+	for letter := range answerLetterSet(answer) {
+		key := string(letter)
+		if _, ok := options[key]; !ok {
+			return nil, fmt.Errorf("answer %s does not match any option", key)
+		}
+		letters = append(letters, key)
+	}
+	sort.Strings(letters)
+
+	qType := "single"
+	switch {
+	case len(letters) > 1:
+		qType = "multiple"
+	case len(options) == 2 && qtiJudgeTexts[strings.ToLower(options["A"])] && qtiJudgeTexts[strings.ToLower(options["B"])]:
+		qType = "judge"
+	}
+
+	return &QuestionData{
+		Type:     qType,
+		Question: stem,
+		Options:  options,
+		Answer:   strings.Join(letters, ""),
+		Category: aikenDefaultCategory,
+	}, nil
+}
+
+// exportAiken 导出为Aiken格式；Aiken只支持单答案选择题，多选题按多个字母写出
+func exportAiken(w io.Writer, filter QuestionFilter) error {
+	bw := bufio.NewWriter(w)
+
+	err := eachQuestion(filter, "category, id", func(q *models.Question) error {
+		if q.Type == "ordering" || q.Type == "matching" {
+			log.Printf("Skipping question in Aiken export: question %d is a %s question", q.ID, q.Type)
+			return nil
+		}
+		options := parseOptions(q.Options)
+		var letters []string
+		for letter := range answerLetterSet(q.Answer) {
+			if _, ok := options[string(letter)]; ok {
+				letters = append(letters, string(letter))
+			}
+		}
+		if len(options) == 0 || len(letters) == 0 {
+			log.Printf("Skipping question in Aiken export: question %d has no options or answer", q.ID)
+			return nil
+		}
+		sort.Strings(letters)
+
+		// Aiken以行为单位，题干和选项中的换行替换为空格
+		fmt.Fprintln(bw, strings.ReplaceAll(q.Question, "\n", " "))
+		for _, key := range sortedKeys(options) {
+			fmt.Fprintf(bw, "%s. %s\n", key, strings.ReplaceAll(options[key], "\n", " "))
+		}
+		_, err := fmt.Fprintf(bw, "ANSWER: %s\n\n", strings.Join(letters, ","))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// sortedKeys 返回排序后的选项键，匹配题的数字编号按数值排在字母之前
+func sortedKeys(options map[string]string) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, aErr := strconv.Atoi(keys[i])
+		b, bErr := strconv.Atoi(keys[j])
+		switch {
+		case aErr == nil && bErr == nil:
+			return a < b
+		case aErr == nil || bErr == nil:
+			return aErr == nil
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
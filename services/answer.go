@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidScore 人工复核得分超出范围
+var ErrInvalidScore = errors.New("score must be between 0 and 1")
+
+// SaveUserAnswer 按判分结果保存答题记录并更新学习记录，examRecordID为0表示练习作答
+func SaveUserAnswer(userID uint, question *models.Question, userAnswer string, grade models.GradeResult, examRecordID uint) (*models.UserAnswer, error) {
+	record := newUserAnswer(userID, question, userAnswer, grade, examRecordID)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		_, err := trackAnswer(tx, record, answerQuality(record))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// trackAnswer 按答题记录更新复习计划、错题本、分类掌握程度和当天学习计划，需在保存答题记录的事务中调用
+func trackAnswer(tx *gorm.DB, answer *models.UserAnswer, quality int) (*models.ReviewCard, error) {
+	card, err := scheduleReview(tx, answer, quality)
+	if err != nil {
+		return nil, err
+	}
+	if err := updateWrongBook(tx, answer); err != nil {
+		return nil, err
+	}
+	if err := updateMastery(tx, answer); err != nil {
+		return nil, err
+	}
+	if err := markPlanItemDone(tx, answer); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// newUserAnswer 按判分结果构造答题记录
+func newUserAnswer(userID uint, question *models.Question, userAnswer string, grade models.GradeResult, examRecordID uint) *models.UserAnswer {
+	return &models.UserAnswer{
+		UserID:       userID,
+		QuestionID:   question.ID,
+		UserAnswer:   userAnswer,
+		IsCorrect:    grade.IsCorrect,
+		Score:        grade.Score,
+		ReviewStatus: grade.ReviewStatus,
+		Category:     question.Category,
+		Revision:     question.Revision,
+		ExamRecordID: examRecordID,
+		AnsweredAt:   time.Now(),
+	}
+}
+
+// ListPendingAnswers 获取人工复核队列，status为空时返回等待复核的记录，questionID为0时不限题目
+func ListPendingAnswers(status string, questionID uint, limit, offset int) ([]models.PendingAnswer, int64, error) {
+	if status == "" {
+		status = ReviewStatusPending
+	}
+
+	query := DB.Table("user_answers ua").
+		Joins("JOIN users u ON ua.user_id = u.id").
+		Where("ua.review_status = ?", status)
+	if questionID > 0 {
+		query = query.Where("ua.question_id = ?", questionID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.PendingAnswer
+	if err := query.Select("ua.*, u.username").
+		Order("ua.answered_at ASC, ua.id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range items {
+		if question, err := Cache.GetQuestion(items[i].QuestionID); err == nil {
+			q := *question
+			items[i].Question = &q
+		}
+	}
+	return items, total, nil
+}
+
+// ReviewAnswer 人工复核答题记录，所属考试的成绩随之更新并通知答题用户
+func ReviewAnswer(answerID, reviewerID uint, req *models.AnswerReviewRequest) (*models.UserAnswer, error) {
+	score := 0.0
+	if *req.IsCorrect {
+		score = 1
+	}
+	if req.Score != nil {
+		score = *req.Score
+	}
+	if score < 0 || score > 1 {
+		return nil, ErrInvalidScore
+	}
+
+	var answer models.UserAnswer
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&answer, answerID).Error; err != nil {
+			return err
+		}
+		// 首次复核时才计入学习记录，重复复核不再调整
+		wasPending := answer.ReviewStatus == ReviewStatusPending
+
+		now := time.Now()
+		answer.IsCorrect = *req.IsCorrect
+		answer.Score = roundScore(score)
+		answer.ReviewStatus = ReviewStatusReviewed
+		answer.ReviewerID = reviewerID
+		answer.ReviewComment = req.Comment
+		answer.ReviewedAt = &now
+		if err := tx.Save(&answer).Error; err != nil {
+			return err
+		}
+
+		if wasPending {
+			if _, err := trackAnswer(tx, &answer, answerQuality(&answer)); err != nil {
+				return err
+			}
+		}
+
+		if answer.ExamRecordID != 0 {
+			if err := recomputeExamRecord(tx, answer.ExamRecordID); err != nil {
+				return err
+			}
+		}
+
+		result := "未通过"
+		if answer.IsCorrect {
+			result = "通过"
+		}
+		message := fmt.Sprintf("你对题目 #%d 的作答已复核：%s，得分 %.0f%%。", answer.QuestionID, result, answer.Score*100)
+		if req.Comment != "" {
+			message += "评语：" + req.Comment
+		}
+		return CreateNotification(tx, &models.Notification{
+			UserID:     answer.UserID,
+			Type:       "answer_reviewed",
+			Title:      "作答已复核",
+			Message:    message,
+			QuestionID: answer.QuestionID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &answer, nil
+}
+
+// recomputeExamRecord 按答题记录重新计算考试的答对数和成绩
+func recomputeExamRecord(tx *gorm.DB, recordID uint) error {
+	var record models.ExamRecord
+	if err := tx.First(&record, recordID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var totals struct {
+		Correct int
+		Score   float64
+	}
+	if err := tx.Model(&models.UserAnswer{}).
+		Select("sum(case when is_correct then 1 else 0 end) as correct, coalesce(sum(score), 0) as score").
+		Where("exam_record_id = ?", recordID).
+		Scan(&totals).Error; err != nil {
+		return err
+	}
+
+	record.CorrectCount = totals.Correct
+	if record.TotalCount > 0 {
+		record.Score = totals.Score / float64(record.TotalCount) * 100
+	}
+	return tx.Save(&record).Error
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// MaxAttachmentSize 单个附件的大小上限
+const MaxAttachmentSize = 5 << 20
+
+// attachmentTypes 允许上传的附件类型及导出时使用的扩展名
+// SVG可内嵌脚本，不在允许范围内
+var attachmentTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// attachmentRefPattern 题目文本中的附件引用
+var attachmentRefPattern = regexp.MustCompile(`/api/attachments/([0-9a-f]{64})`)
+
+var (
+	// ErrAttachmentTooLarge 附件超出大小限制
+	ErrAttachmentTooLarge = fmt.Errorf("attachment cannot exceed %d MB", MaxAttachmentSize>>20)
+	// ErrUnsupportedAttachment 附件类型不在允许范围内
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type, allowed: png, jpeg, gif, webp, pdf")
+	// ErrAttachmentInUse 附件仍被题目引用，不能删除
+	ErrAttachmentInUse = errors.New("attachment is still referenced by questions")
+	// ErrAttachmentMissing 题目引用了不存在的附件
+	ErrAttachmentMissing = errors.New("referenced attachment does not exist")
+	// ErrAttachmentChecksum 导入的附件内容与SHA-256不符
+	ErrAttachmentChecksum = errors.New("attachment content does not match its sha256")
+)
+
+// AttachmentData 题库文件中的附件，JSON导出只含元数据，bundle格式在压缩包中附带文件内容
+type AttachmentData struct {
+	SHA256      string `json:"sha256"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"-"`
+}
+
+// AttachmentReference 返回可直接写入题干或选项的附件引用，图片为 ![说明](url)，其他附件为 [文件名](url)
+func AttachmentReference(attachment *models.Attachment) string {
+	label := strings.NewReplacer("[", "", "]", "").Replace(attachment.Filename)
+	if !strings.HasPrefix(attachment.ContentType, "image/") {
+		return fmt.Sprintf("[%s](/api/attachments/%s)", label, attachment.SHA256)
+	}
+	return fmt.Sprintf("![%s](/api/attachments/%s)", strings.TrimSuffix(label, path.Ext(label)), attachment.SHA256)
+}
+
+// SaveAttachment 保存附件，内容相同的附件只保存一份
+func SaveAttachment(filename string, data []byte, uploaderID uint) (*models.Attachment, error) {
+	return saveAttachment(DB, filename, data, uploaderID)
+}
+
+// saveAttachment 在指定事务中保存附件
+func saveAttachment(tx *gorm.DB, filename string, data []byte, uploaderID uint) (*models.Attachment, error) {
+	if len(data) > MaxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := attachmentTypes[contentType]; !ok {
+		return nil, ErrUnsupportedAttachment
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var attachment models.Attachment
+	err := tx.Omit("data").Where("sha256 = ?", hash).First(&attachment).Error
+	if err == nil {
+		return &attachment, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	attachment = models.Attachment{
+		SHA256:      hash,
+		Filename:    path.Base(strings.ReplaceAll(filename, "\\", "/")),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Data:        data,
+		UploaderID:  uploaderID,
+	}
+	if err := tx.Create(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// GetAttachment 按SHA-256获取附件及内容
+func GetAttachment(hash string) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := DB.Where("sha256 = ?", strings.ToLower(hash)).First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListAttachments 分页获取附件列表（不含内容）及引用它们的题目
+func ListAttachments(limit, offset int) ([]models.AttachmentInfo, int64, error) {
+	var total int64
+	if err := DB.Model(&models.Attachment{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attachments []models.Attachment
+	if err := DB.Omit("data").
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&attachments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]models.AttachmentInfo, len(attachments))
+	for i := range attachments {
+		ids, err := attachmentQuestionIDs(attachments[i].SHA256)
+		if err != nil {
+			return nil, 0, err
+		}
+		items[i] = models.AttachmentInfo{
+			Attachment:  attachments[i],
+			Reference:   AttachmentReference(&attachments[i]),
+			QuestionIDs: ids,
+		}
+	}
+	return items, total, nil
+}
+
+// DeleteAttachment 删除未被任何题目引用的附件
+func DeleteAttachment(hash string) error {
+	hash = strings.ToLower(hash)
+	var attachment models.Attachment
+	if err := DB.Omit("data").Where("sha256 = ?", hash).First(&attachment).Error; err != nil {
+		return err
+	}
+
+	ids, err := attachmentQuestionIDs(hash)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return ErrAttachmentInUse
+	}
+	return DB.Delete(&attachment).Error
+}
+
+// attachmentQuestionIDs 查找题干、选项或解析中引用了附件的题目
+func attachmentQuestionIDs(hash string) ([]uint, error) {
+	pattern := "%/api/attachments/" + hash + "%"
+	var ids []uint
+	err := DB.Model(&models.Question{}).
+		Where("question LIKE ? OR options LIKE ? OR explanation LIKE ?", pattern, pattern, pattern).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// attachmentRefs 提取文本中引用的附件SHA-256，按首次出现顺序去重
+func attachmentRefs(texts ...string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, text := range texts {
+		for _, m := range attachmentRefPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	return refs
+}
+
+// questionAttachmentRefs 题目引用的附件
+func questionAttachmentRefs(q *models.Question) []string {
+	return attachmentRefs(q.Question, q.Options, q.Explanation)
+}
+
+// questionDataAttachmentRefs 题库文件中题目引用的附件
+func questionDataAttachmentRefs(qd *QuestionData) []string {
+	texts := []string{qd.Question, qd.Explanation}
+	for _, option := range qd.Options {
+		texts = append(texts, option)
+	}
+	return attachmentRefs(texts...)
+}
+
+// checkAttachmentRefs 确认引用的附件都已存在
+func checkAttachmentRefs(tx *gorm.DB, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Attachment{}).Where("sha256 IN ?", refs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(refs) {
+		return ErrAttachmentMissing
+	}
+	return nil
+}
+
+// attachmentMetadata 获取题目引用的附件元数据，用于导出
+func attachmentMetadata(refs []string) []AttachmentData {
+	if len(refs) == 0 {
+		return nil
+	}
+	var attachments []models.Attachment
+	if err := DB.Omit("data").Where("sha256 IN ?", refs).Find(&attachments).Error; err != nil {
+		return nil
+	}
+
+	bySHA := make(map[string]*models.Attachment, len(attachments))
+	for i := range attachments {
+		bySHA[attachments[i].SHA256] = &attachments[i]
+	}
+	var result []AttachmentData
+	for _, ref := range refs {
+		if a, ok := bySHA[ref]; ok {
+			result = append(result, AttachmentData{SHA256: a.SHA256, Filename: a.Filename, ContentType: a.ContentType})
+		}
+	}
+	return result
+}
+
+// saveImportedAttachments 在导入事务中保存题库文件中附带内容的附件，内容须与声明的SHA-256一致
+func saveImportedAttachments(tx *gorm.DB, questionsData []QuestionData, uploaderID uint) error {
+	for i, qd := range questionsData {
+		for _, a := range qd.Attachments {
+			if len(a.Data) == 0 {
+				continue
+			}
+			sum := sha256.Sum256(a.Data)
+			if hex.EncodeToString(sum[:]) != strings.ToLower(a.SHA256) {
+				return fmt.Errorf("question #%d: %w: %s", i+1, ErrAttachmentChecksum, a.SHA256)
+			}
+			if _, err := saveAttachment(tx, a.Filename, a.Data, uploaderID); err != nil {
+				return fmt.Errorf("question #%d: attachment %s: %w", i+1, a.SHA256, err)
+			}
+		}
+	}
+	return nil
+}
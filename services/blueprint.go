@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"quiz-system/models"
+)
+
+// maxBlueprintQuestions 单次组卷的题目数上限
+const maxBlueprintQuestions = 300
+
+// ErrNoBlueprintQuestions 没有符合组卷规则的题目
+var ErrNoBlueprintQuestions = errors.New("no questions match the exam blueprint")
+
+// examBlueprints 内置组卷规则，键为考试类型
+var examBlueprints = map[string]models.ExamBlueprint{
+	// 练习模式：随机20题，不限时间
+	"practice": {
+		Name:             "practice",
+		Duration:         0,
+		Sections:         []models.BlueprintSection{{Count: 20}},
+		DistinctClusters: true,
+	},
+	// 模拟考试：单选60题、多选60题、判断60题，共180题，180分钟
+	"mock_exam": {
+		Name:     "mock_exam",
+		Duration: 180,
+		Sections: []models.BlueprintSection{
+			{Type: "single", Count: 60},
+			{Type: "multiple", Count: 60},
+			{Type: "judge", Count: 60},
+		},
+		DistinctClusters: true,
+	},
+}
+
+// GetExamBlueprint 获取内置组卷规则的副本
+func GetExamBlueprint(examType string) (*models.ExamBlueprint, bool) {
+	blueprint, ok := examBlueprints[examType]
+	if !ok {
+		return nil, false
+	}
+	blueprint.Sections = append([]models.BlueprintSection(nil), blueprint.Sections...)
+	return &blueprint, true
+}
+
+// ValidateBlueprint 校验自定义组卷规则
+func ValidateBlueprint(blueprint *models.ExamBlueprint) error {
+	if len(blueprint.Sections) == 0 {
+		return errors.New("blueprint must have at least one section")
+	}
+	if blueprint.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+
+	total := 0
+	for i, section := range blueprint.Sections {
+		if section.Count <= 0 {
+			return fmt.Errorf("section %d: count must be positive", i+1)
+		}
+		if section.Type != "" && questionTypeNames[section.Type] == "" {
+			return fmt.Errorf("section %d: unknown question type %q", i+1, section.Type)
+		}
+		for _, difficulty := range []int{section.MinDifficulty, section.MaxDifficulty} {
+			if difficulty != 0 && !validDifficulty(difficulty) {
+				return fmt.Errorf("section %d: %v", i+1, ErrInvalidDifficulty)
+			}
+		}
+		if section.MinDifficulty != 0 && section.MaxDifficulty != 0 && section.MinDifficulty > section.MaxDifficulty {
+			return fmt.Errorf("section %d: min_difficulty cannot be greater than max_difficulty", i+1)
+		}
+		total += section.Count
+	}
+	if total > maxBlueprintQuestions {
+		return fmt.Errorf("blueprint cannot have more than %d questions", maxBlueprintQuestions)
+	}
+	return nil
+}
+
+// NarrowBlueprint 将额外的筛选条件应用到组卷规则的每个部分
+// 标签与各部分原有标签合并；分类和难度仅在原部分未指定时生效；筛选条件要求去重时整份试卷去重
+func NarrowBlueprint(blueprint *models.ExamBlueprint, filter QuestionFilter) {
+	if filter.DistinctClusters {
+		blueprint.DistinctClusters = true
+	}
+	for i := range blueprint.Sections {
+		section := &blueprint.Sections[i]
+		section.Tags = NormalizeTags(append(append([]string{}, section.Tags...), filter.Tags...))
+		if section.Category == "" {
+			section.Category = filter.Category
+		}
+		if section.MinDifficulty == 0 && section.MaxDifficulty == 0 {
+			section.MinDifficulty = filter.MinDifficulty
+			section.MaxDifficulty = filter.MaxDifficulty
+		}
+	}
+}
+
+// SelectBlueprintQuestions 按组卷规则随机抽题，各部分之间不重复；符合条件的题目不足的部分按实际数量抽取，并在shortfalls中列出
+func SelectBlueprintQuestions(blueprint *models.ExamBlueprint) ([]models.Question, []models.BlueprintShortfall, error) {
+	var questions []models.Question
+	var selected []uint
+	var shortfalls []models.BlueprintShortfall
+
+	for i, section := range blueprint.Sections {
+		filter := QuestionFilter{
+			Category:         section.Category,
+			Type:             section.Type,
+			Tags:             NormalizeTags(section.Tags),
+			MinDifficulty:    section.MinDifficulty,
+			MaxDifficulty:    section.MaxDifficulty,
+			ExcludeIDs:       selected,
+			DistinctClusters: blueprint.DistinctClusters,
+		}
+		sectionQuestions, err := Cache.FindQuestions(filter, section.Count, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(sectionQuestions) < section.Count {
+			log.Printf("Blueprint %q section %d: only %d of %d questions available", blueprint.Name, i+1, len(sectionQuestions), section.Count)
+			shortfalls = append(shortfalls, models.BlueprintShortfall{
+				Section:   i + 1,
+				Requested: section.Count,
+				Selected:  len(sectionQuestions),
+			})
+		}
+		for _, q := range sectionQuestions {
+			selected = append(selected, q.ID)
+		}
+		questions = append(questions, sectionQuestions...)
+	}
+
+	if len(questions) == 0 {
+		return nil, nil, ErrNoBlueprintQuestions
+	}
+	return questions, shortfalls, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxNoteLength 个人笔记的最大字数
+const maxNoteLength = 2000
+
+// ErrNoteTooLong 笔记超出长度限制
+var ErrNoteTooLong = fmt.Errorf("note cannot exceed %d characters", maxNoteLength)
+
+// AddBookmark 收藏题目，重复收藏不报错
+func AddBookmark(userID, questionID uint) error {
+	if _, err := Cache.GetQuestion(questionID); err != nil {
+		return err
+	}
+
+	bookmark := models.Bookmark{UserID: userID, QuestionID: questionID}
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error
+}
+
+// RemoveBookmark 取消收藏
+func RemoveBookmark(userID, questionID uint) error {
+	return DB.Where("user_id = ? AND question_id = ?", userID, questionID).
+		Delete(&models.Bookmark{}).Error
+}
+
+// SaveNote 保存个人笔记，内容为空时删除笔记
+func SaveNote(userID, questionID uint, content string) (*models.QuestionNote, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, DeleteNote(userID, questionID)
+	}
+	if utf8.RuneCountInString(content) > maxNoteLength {
+		return nil, ErrNoteTooLong
+	}
+	if _, err := Cache.GetQuestion(questionID); err != nil {
+		return nil, err
+	}
+
+	var note models.QuestionNote
+	err := DB.Where("user_id = ? AND question_id = ?", userID, questionID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		note = models.QuestionNote{UserID: userID, QuestionID: questionID, Content: content}
+		if err := DB.Create(&note).Error; err != nil {
+			return nil, err
+		}
+		return &note, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	note.Content = content
+	if err := DB.Save(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// DeleteNote 删除个人笔记
+func DeleteNote(userID, questionID uint) error {
+	return DB.Where("user_id = ? AND question_id = ?", userID, questionID).
+		Delete(&models.QuestionNote{}).Error
+}
+
+// AttachPersonalData 为题目附加当前用户的书签和笔记
+func AttachPersonalData(userID uint, questions []models.Question) ([]models.QuestionView, error) {
+	views := make([]models.QuestionView, len(questions))
+	if len(questions) == 0 {
+		return views, nil
+	}
+
+	ids := make([]uint, len(questions))
+	for i := range questions {
+		ids[i] = questions[i].ID
+		views[i].Question = questions[i]
+	}
+
+	var bookmarked []uint
+	if err := DB.Model(&models.Bookmark{}).
+		Where("user_id = ? AND question_id IN ?", userID, ids).
+		Pluck("question_id", &bookmarked).Error; err != nil {
+		return nil, err
+	}
+	var notes []models.QuestionNote
+	if err := DB.Where("user_id = ? AND question_id IN ?", userID, ids).
+		Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	bookmarkSet := make(map[uint]bool, len(bookmarked))
+	for _, id := range bookmarked {
+		bookmarkSet[id] = true
+	}
+	noteMap := make(map[uint]string, len(notes))
+	for _, note := range notes {
+		noteMap[note.QuestionID] = note.Content
+	}
+
+	for i := range views {
+		views[i].Bookmarked = bookmarkSet[views[i].ID]
+		views[i].Note = noteMap[views[i].ID]
+	}
+	return views, nil
+}
+
+// bookmarkQuery 用户收藏题目的查询，可按分类（含分类树子节点）筛选
+func bookmarkQuery(userID uint, category string) *gorm.DB {
+	return DB.Model(&models.Question{}).
+		Joins("JOIN bookmarks b ON b.question_id = questions.id AND b.user_id = ?", userID).
+		Scopes(CategoryScope("questions.category", category))
+}
+
+// GetBookmarks 获取收藏的题目（最近收藏的在前）及总数
+func GetBookmarks(userID uint, category string, limit, offset int) ([]models.QuestionView, int64, error) {
+	var total int64
+	if err := bookmarkQuery(userID, category).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var questions []models.Question
+	if err := bookmarkQuery(userID, category).
+		Order("b.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&questions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	views, err := AttachPersonalData(userID, questions)
+	if err != nil {
+		return nil, 0, err
+	}
+	return views, total, nil
+}
+
+// GetRandomBookmarkedQuestions 从收藏中随机抽取题目
+func GetRandomBookmarkedQuestions(userID uint, category string, count int) ([]models.Question, error) {
+	var questions []models.Question
+	if err := bookmarkQuery(userID, category).
+		Order("RANDOM()").
+		Limit(count).
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
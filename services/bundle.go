@@ -0,0 +1,128 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"quiz-system/models"
+)
+
+// bundleQuestionsFile 题库压缩包中的题目文件，格式与JSON导出相同
+const bundleQuestionsFile = "questions.json"
+
+// bundleAttachmentDir 题库压缩包中存放附件的目录，文件名为 <sha256><扩展名>
+const bundleAttachmentDir = "attachments/"
+
+// exportBundle 导出为附带附件的题库压缩包：questions.json 加 attachments/ 目录
+func exportBundle(w io.Writer, filter QuestionFilter) error {
+	zw := zip.NewWriter(w)
+
+	qw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     bundleQuestionsFile,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := exportJSON(qw, filter); err != nil {
+		return err
+	}
+
+	var refs []string
+	seen := make(map[string]bool)
+	err = eachQuestion(filter, "id", func(q *models.Question) error {
+		for _, ref := range questionAttachmentRefs(q) {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 附件逐个读取写入，避免一次性加载所有附件内容
+	for _, ref := range refs {
+		attachment, err := GetAttachment(ref)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %v", ref, err)
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     bundleAttachmentDir + ref + attachmentTypes[attachment.ContentType],
+			Method:   zip.Store,
+			Modified: attachment.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(attachment.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// parseBundle 解析题库压缩包，题目引用的附件从 attachments/ 目录读取
+func parseBundle(data []byte) ([]QuestionData, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+
+	var questionsFile *zip.File
+	files := make(map[string]*zip.File)
+	for _, f := range reader.File {
+		name := path.Clean(f.Name)
+		switch {
+		case name == bundleQuestionsFile:
+			questionsFile = f
+		case strings.HasPrefix(name, bundleAttachmentDir):
+			base := path.Base(name)
+			files[strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))] = f
+		}
+	}
+	if questionsFile == nil {
+		return nil, fmt.Errorf("invalid bundle: %s not found", bundleQuestionsFile)
+	}
+
+	content, err := readZipFile(questionsFile)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := ParseQuestions(bytes.NewReader(content), FormatJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range questions {
+		metadata := make(map[string]AttachmentData)
+		for _, a := range questions[i].Attachments {
+			metadata[strings.ToLower(a.SHA256)] = a
+		}
+
+		var attachments []AttachmentData
+		for _, ref := range questionDataAttachmentRefs(&questions[i]) {
+			a := metadata[ref]
+			a.SHA256 = ref
+			if f, ok := files[ref]; ok {
+				if f.UncompressedSize64 > MaxAttachmentSize {
+					return nil, fmt.Errorf("attachment %s: %v", ref, ErrAttachmentTooLarge)
+				}
+				if a.Data, err = readZipFile(f); err != nil {
+					return nil, fmt.Errorf("attachment %s: %v", ref, err)
+				}
+			}
+			attachments = append(attachments, a)
+		}
+		questions[i].Attachments = attachments
+	}
+	return questions, nil
+}
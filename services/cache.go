@@ -7,6 +7,7 @@ import (
 
 	"quiz-system/models"
 	lru "github.com/hashicorp/golang-lru/v2"
+	"gorm.io/gorm/clause"
 )
 
 // CacheService 缓存服务
@@ -68,37 +69,59 @@ func (c *CacheService) GetQuestion(id uint) (*models.Question, error) {
 	return &question, nil
 }
 
-// GetQuestionsByCategory 按分类获取题目
-func (c *CacheService) GetQuestionsByCategory(category string, limit int) ([]models.Question, error) {
-	var questions []models.Question
-	query := DB.Where("category = ?", category)
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-	
-	if err := query.Find(&questions).Error; err != nil {
-		return nil, err
-	}
-
-	// 将查询到的题目添加到缓存
-	for i := range questions {
-		c.questionCache.Add(questions[i].ID, &questions[i])
-	}
+// InvalidateQuestion 题目被编辑后从缓存中移除
+func (c *CacheService) InvalidateQuestion(id uint) {
+	c.questionCache.Remove(id)
+}
 
-	return questions, nil
+// GetQuestionsByCategory 按分类获取题目，分类树的父节点包含所有子分类
+func (c *CacheService) GetQuestionsByCategory(category string, limit int) ([]models.Question, error) {
+	return c.FindQuestions(QuestionFilter{Category: category}, limit, false)
 }
 
-// GetRandomQuestions 获取随机题目
-func (c *CacheService) GetRandomQuestions(qType string, count int) ([]models.Question, error) {
+// distinctOverFetch 同簇去重时候选题目相对limit的倍数
+const distinctOverFetch = 4
+
+// FindQuestions 按筛选条件获取题目，random为true时随机抽取
+func (c *CacheService) FindQuestions(filter QuestionFilter, limit int, random bool) ([]models.Question, error) {
 	var questions []models.Question
-	
-	query := DB.Order("RANDOM()").Limit(count)
-	if qType != "" {
-		query = query.Where("type = ?", qType)
+
+	var clusterOf map[uint]int
+	if random && filter.DistinctClusters {
+		var err error
+		if clusterOf, err = DuplicateClusterIndex(); err != nil {
+			return nil, err
+		}
 	}
 	
-	if err := query.Find(&questions).Error; err != nil {
-		return nil, err
+	query := filter.apply(DB.Model(&models.Question{}))
+	if random && filter.UnseenFirstUserID > 0 {
+		// 作答过的题目排在后面，两组内各自随机
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "CASE WHEN id IN (?) THEN 1 ELSE 0 END, RANDOM()",
+			Vars:               []interface{}{answeredQuestions(filter.UnseenFirstUserID)},
+			WithoutParentheses: true,
+		}})
+	} else if random {
+		query = query.Order("RANDOM()")
+	}
+
+	if clusterOf == nil {
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		if err := query.Find(&questions).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		// 去重时同簇题目会被跳过，一次多取几倍候选；重复簇过多时返回的题目可能少于limit
+		if limit > 0 {
+			query = query.Limit(limit * distinctOverFetch)
+		}
+		if err := query.Find(&questions).Error; err != nil {
+			return nil, err
+		}
+		questions = distinctClusterQuestions(questions, clusterOf, filter.ExcludeIDs, limit)
 	}
 
 	// 将查询到的题目添加到缓存
@@ -113,6 +136,7 @@ func (c *CacheService) GetRandomQuestions(qType string, count int) ([]models.Que
 type UserSession struct {
 	UserID    uint      `json:"user_id"`
 	Username  string    `json:"username"`
+	IsAdmin   bool      `json:"is_admin"`
 	LoginTime time.Time `json:"login_time"`
 	LastSeen  time.Time `json:"last_seen"`
 }
@@ -167,6 +191,23 @@ func (c *CacheService) UpdateExamSession(sessionID string, session *models.ExamS
 	c.examSessions.Store(sessionID, session)
 }
 
+// CompleteExamSession 将考试会话标记为已完成，会话不存在或已完成时返回false，防止重复交卷
+func (c *CacheService) CompleteExamSession(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.examSessions.Load(sessionID)
+	if !ok {
+		return false
+	}
+	examSession := session.(*models.ExamSession)
+	if examSession.IsCompleted {
+		return false
+	}
+	examSession.IsCompleted = true
+	return true
+}
+
 // DeleteExamSession 删除考试会话
 func (c *CacheService) DeleteExamSession(sessionID string) {
 	c.examSessions.Delete(sessionID)
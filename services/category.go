@@ -0,0 +1,291 @@
+package services
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// categoryTreeFile 可选的分类树配置文件，未列出的分类按分类名自动归类
+const categoryTreeFile = "category_tree.json"
+
+// CategoryGroupConfig 分类树配置中的分组
+// Categories 为完整分类名，或以*结尾的前缀（如 "GM-T*"）；按配置顺序匹配，先匹配者优先
+type CategoryGroupConfig struct {
+	Name       string                `json:"name"`
+	Categories []string              `json:"categories,omitempty"`
+	Children   []CategoryGroupConfig `json:"children,omitempty"`
+}
+
+// standardCategoryPattern 标准类分类，如 "GM-T 0115-2021"、"GBT 39786"、"GM-Z 4001"
+var standardCategoryPattern = regexp.MustCompile(`^(GM|GB)[-/]?([TZ])\s*\d`)
+
+var (
+	categoryConfigOnce sync.Once
+	categoryConfig     []CategoryGroupConfig
+)
+
+// categoryRule 配置中的分类到分组节点的映射
+type categoryRule struct {
+	pattern string
+	node    *models.CategoryNode
+}
+
+// loadCategoryTreeConfig 读取分类树配置，文件不存在时返回nil
+func loadCategoryTreeConfig() []CategoryGroupConfig {
+	categoryConfigOnce.Do(func() {
+		data, err := ioutil.ReadFile(categoryTreeFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Warning: failed to read %s: %v", categoryTreeFile, err)
+			}
+			return
+		}
+		if err := json.Unmarshal(data, &categoryConfig); err != nil {
+			log.Printf("Warning: failed to parse %s: %v", categoryTreeFile, err)
+			categoryConfig = nil
+			return
+		}
+		log.Printf("Loaded category tree config from %s", categoryTreeFile)
+	})
+	return categoryConfig
+}
+
+var (
+	categoryTreeMu    sync.Mutex
+	categoryTreeCache []*models.CategoryNode // 题目变更后失效
+)
+
+// InvalidateCategoryTree 题目新增或编辑后清除分类树，下次使用时重新构建
+func InvalidateCategoryTree() {
+	categoryTreeMu.Lock()
+	categoryTreeCache = nil
+	categoryTreeMu.Unlock()
+}
+
+// GetCategoryTree 获取分类树，父节点的题目数为所有子分类之和
+// 返回的分类树在题目变更前被所有调用方共用，不能修改
+func GetCategoryTree() ([]*models.CategoryNode, error) {
+	categoryTreeMu.Lock()
+	defer categoryTreeMu.Unlock()
+	if categoryTreeCache != nil {
+		return categoryTreeCache, nil
+	}
+
+	var categories []models.Category
+	if err := DB.Model(&models.Question{}).
+		Select("category as name, count(*) as count").
+		Group("category").
+		Order("category").
+		Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	categoryTreeCache = buildCategoryTree(categories, loadCategoryTreeConfig())
+	return categoryTreeCache, nil
+}
+
+// buildCategoryTree 先放入配置中的分组，再将每个分类挂到配置分组或推导出的分组下
+func buildCategoryTree(categories []models.Category, config []CategoryGroupConfig) []*models.CategoryNode {
+	root := &models.CategoryNode{}
+	var rules []categoryRule
+	addCategoryGroups(root, config, &rules)
+
+	for _, category := range categories {
+		parent, name := root, category.Name
+		if node := matchCategoryRule(rules, category.Name); node != nil {
+			parent = node
+		} else {
+			var groups []string
+			groups, name = deriveCategoryPath(category.Name)
+			for _, group := range groups {
+				parent = categoryChild(parent, group)
+			}
+		}
+
+		parent.Children = append(parent.Children, &models.CategoryNode{
+			Name:  name,
+			Path:  category.Name,
+			Count: category.Count,
+			Leaf:  true,
+		})
+	}
+
+	sumCategoryCounts(root)
+	return root.Children
+}
+
+// addCategoryGroups 按配置创建分组节点并收集匹配规则
+func addCategoryGroups(parent *models.CategoryNode, groups []CategoryGroupConfig, rules *[]categoryRule) {
+	for _, group := range groups {
+		if strings.TrimSpace(group.Name) == "" {
+			continue
+		}
+		node := categoryChild(parent, strings.TrimSpace(group.Name))
+		for _, pattern := range group.Categories {
+			*rules = append(*rules, categoryRule{pattern: pattern, node: node})
+		}
+		addCategoryGroups(node, group.Children, rules)
+	}
+}
+
+// matchCategoryRule 查找分类在配置中所属的分组
+func matchCategoryRule(rules []categoryRule, category string) *models.CategoryNode {
+	for _, rule := range rules {
+		if prefix, ok := strings.CutSuffix(rule.pattern, "*"); ok {
+			if strings.HasPrefix(category, prefix) {
+				return rule.node
+			}
+		} else if rule.pattern == category {
+			return rule.node
+		}
+	}
+	return nil
+}
+
+// deriveCategoryPath 从分类名推导分组
+// 标准按代号归入 GM/T、GB/T、GM/Z；其余按"-"切分，不含字母数字的前缀作为上级分组，
+// 如 "算法相关-SM2" 归入 "算法相关"，而 "GM-T 0115-2021" 中的"-"不作为分隔
+func deriveCategoryPath(category string) ([]string, string) {
+	if m := standardCategoryPattern.FindStringSubmatch(category); m != nil {
+		return []string{m[1] + "/" + m[2]}, category
+	}
+
+	parts := strings.Split(category, "-")
+	var groups []string
+	for len(parts) > 1 {
+		prefix := strings.TrimSpace(parts[0])
+		if prefix == "" || containsASCIIAlnum(prefix) {
+			break
+		}
+		groups = append(groups, prefix)
+		parts = parts[1:]
+	}
+
+	name := strings.TrimSpace(strings.Join(parts, "-"))
+	if name == "" {
+		return nil, category
+	}
+	return groups, name
+}
+
+// containsASCIIAlnum 是否包含ASCII字母或数字
+func containsASCIIAlnum(s string) bool {
+	for _, r := range s {
+		if r < 128 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryChild 获取或创建分组子节点，分组路径为各级名称以"/"连接
+func categoryChild(parent *models.CategoryNode, name string) *models.CategoryNode {
+	for _, child := range parent.Children {
+		if !child.Leaf && child.Name == name {
+			return child
+		}
+	}
+
+	path := name
+	if parent.Path != "" {
+		path = parent.Path + "/" + name
+	}
+	child := &models.CategoryNode{Name: name, Path: path}
+	parent.Children = append(parent.Children, child)
+	return child
+}
+
+// sumCategoryCounts 汇总分组的题目数，并移除没有题目的分组
+func sumCategoryCounts(node *models.CategoryNode) int {
+	if node.Leaf {
+		return node.Count
+	}
+
+	node.Count = 0
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		if count := sumCategoryCounts(child); count > 0 || child.Leaf {
+			node.Count += count
+			children = append(children, child)
+		}
+	}
+	node.Children = children
+	return node.Count
+}
+
+// findCategoryNode 按路径查找节点，分组优先于同名分类
+func findCategoryNode(nodes []*models.CategoryNode, path string) *models.CategoryNode {
+	var leaf *models.CategoryNode
+	var walk func(nodes []*models.CategoryNode) *models.CategoryNode
+	walk = func(nodes []*models.CategoryNode) *models.CategoryNode {
+		for _, node := range nodes {
+			if node.Path == path {
+				if !node.Leaf {
+					return node
+				}
+				leaf = node
+			}
+			if found := walk(node.Children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	if group := walk(nodes); group != nil {
+		return group
+	}
+	return leaf
+}
+
+// collectCategories 收集节点下的所有实际分类
+func collectCategories(node *models.CategoryNode, categories []string) []string {
+	if node.Leaf {
+		return append(categories, node.Path)
+	}
+	for _, child := range node.Children {
+		categories = collectCategories(child, categories)
+	}
+	return categories
+}
+
+// ExpandCategory 将分类树节点展开为其包含的所有分类；不在树中的分类原样返回
+func ExpandCategory(category string) ([]string, error) {
+	tree, err := GetCategoryTree()
+	if err != nil {
+		return nil, err
+	}
+
+	node := findCategoryNode(tree, category)
+	if node == nil {
+		return []string{category}, nil
+	}
+	return collectCategories(node, nil), nil
+}
+
+// CategoryScope 按分类筛选的查询范围，传入父节点时包含其所有子分类；category为空时不筛选
+func CategoryScope(column, category string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if category == "" {
+			return db
+		}
+
+		categories, err := ExpandCategory(category)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if len(categories) == 1 {
+			return db.Where(column+" = ?", categories[0])
+		}
+		return db.Where(column+" IN ?", categories)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"sort"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// answeredQuestions 用户作答过的题目ID子查询，含等待人工复核的答题，考试中未作答的空白记录不算
+func answeredQuestions(userID uint) *gorm.DB {
+	return DB.Model(&models.UserAnswer{}).Select("DISTINCT question_id").Where("user_id = ? AND user_answer <> ''", userID)
+}
+
+// GetCoverage 统计用户对题库的覆盖情况，category可为分类树父节点，为空时统计全部题目
+func GetCoverage(userID uint, category string) (*models.Coverage, error) {
+	var rows []struct {
+		Category string
+		Type     string
+		Total    int
+		Seen     int
+	}
+	if err := DB.Table("questions q").
+		Select("q.category, q.type, count(*) AS total, sum(case when a.question_id IS NOT NULL then 1 else 0 end) AS seen").
+		Joins("LEFT JOIN (?) AS a ON a.question_id = q.id", answeredQuestions(userID)).
+		Scopes(CategoryScope("q.category", category)).
+		Group("q.category, q.type").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	coverage := &models.Coverage{}
+	categories := make(map[string]*models.CoverageStats)
+	types := make(map[string]*models.CoverageStats)
+	for _, row := range rows {
+		addCoverage(&coverage.CoverageStats, row.Total, row.Seen)
+		addCoverage(coverageEntry(categories, row.Category), row.Total, row.Seen)
+		addCoverage(coverageEntry(types, row.Type), row.Total, row.Seen)
+	}
+
+	finishCoverage(&coverage.CoverageStats)
+	coverage.Categories = sortedCoverage(categories)
+	coverage.Types = sortedCoverage(types)
+	return coverage, nil
+}
+
+// coverageEntry 获取或创建指定名称的覆盖统计
+func coverageEntry(entries map[string]*models.CoverageStats, name string) *models.CoverageStats {
+	entry, ok := entries[name]
+	if !ok {
+		entry = &models.CoverageStats{Name: name}
+		entries[name] = entry
+	}
+	return entry
+}
+
+// addCoverage 累加题目数和作答过的题目数
+func addCoverage(stats *models.CoverageStats, total, seen int) {
+	stats.Total += total
+	stats.Seen += seen
+}
+
+// finishCoverage 计算未作答题目数和覆盖率
+func finishCoverage(stats *models.CoverageStats) {
+	stats.Unseen = stats.Total - stats.Seen
+	if stats.Total > 0 {
+		stats.Coverage = float64(stats.Seen) / float64(stats.Total) * 100
+	}
+}
+
+// sortedCoverage 计算各项覆盖率并按名称排序
+func sortedCoverage(entries map[string]*models.CoverageStats) []models.CoverageStats {
+	result := make([]models.CoverageStats, 0, len(entries))
+	for _, entry := range entries {
+		finishCoverage(entry)
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
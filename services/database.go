@@ -2,11 +2,11 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
@@ -42,12 +42,34 @@ func InitDatabase() error {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 	
+	// 得分字段是否已存在，只在首次加入时迁移旧记录
+	hadScore := DB.Migrator().HasColumn(&models.UserAnswer{}, "score")
+
 	// 自动迁移数据表
 	err = DB.AutoMigrate(
 		&models.Question{},
+		&models.QuestionRevision{},
+		&models.QuestionTag{},
 		&models.User{},
 		&models.UserAnswer{},
 		&models.ExamRecord{},
+		&models.QuestionReport{},
+		&models.Notification{},
+		&models.CommunityExplanation{},
+		&models.ExplanationVote{},
+		&models.Bookmark{},
+		&models.QuestionNote{},
+		&models.Attachment{},
+		&models.PracticePosition{},
+		&models.Standard{},
+		&models.StandardClause{},
+		&models.QuestionClause{},
+		&models.ReviewCard{},
+		&models.WrongBookEntry{},
+		&models.CategoryMastery{},
+		&models.StudySettings{},
+		&models.StudyPlan{},
+		&models.StudyPlanItem{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
@@ -58,6 +80,30 @@ func InitDatabase() error {
 		return fmt.Errorf("failed to create indexes: %v", err)
 	}
 	
+	// 得分字段加入前的答对记录按满分计，之后得分为0的答对记录可能是人工评分结果，不再改动
+	if !hadScore {
+		if err := DB.Model(&models.UserAnswer{}).
+			Where("is_correct = ? AND score = 0", true).
+			Update("score", 1).Error; err != nil {
+			return fmt.Errorf("failed to migrate answer scores: %v", err)
+		}
+	}
+	
+	// 版本字段加入前的答题记录按题目初始版本判分
+	if err := DB.Model(&models.UserAnswer{}).
+		Where("revision IS NULL").
+		Update("revision", 1).Error; err != nil {
+		return fmt.Errorf("failed to migrate answer revisions: %v", err)
+	}
+	
+	// 全文索引，FTS5不可用时搜索退回LIKE查询
+	if err := initSearchIndex(); err != nil {
+		return fmt.Errorf("failed to initialize search index: %v", err)
+	}
+	if err := initClauseSearchIndex(); err != nil {
+		return fmt.Errorf("failed to initialize clause search index: %v", err)
+	}
+	
 	// 检查是否需要导入题库数据
 	var count int64
 	DB.Model(&models.Question{}).Count(&count)
@@ -73,6 +119,26 @@ func InitDatabase() error {
 		log.Printf("Database already contains %d questions", count)
 	}
 	
+	// 按已有答题记录生成复习计划
+	if err := backfillReviewCards(); err != nil {
+		return fmt.Errorf("failed to build review schedule: %v", err)
+	}
+	
+	// 按已有答题记录生成错题本
+	if err := backfillWrongBook(); err != nil {
+		return fmt.Errorf("failed to build wrong-question book: %v", err)
+	}
+	
+	// 按已有答题记录估计分类掌握程度
+	if err := backfillCategoryMastery(); err != nil {
+		return fmt.Errorf("failed to estimate category mastery: %v", err)
+	}
+	
+	// 按以标准号命名的分类初始化标准目录
+	if err := seedStandardsFromCategories(); err != nil {
+		return fmt.Errorf("failed to seed standards: %v", err)
+	}
+	
 	log.Println("Database initialized successfully")
 	return nil
 }
@@ -82,8 +148,10 @@ func createIndexes() error {
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_questions_category ON questions(category)",
 		"CREATE INDEX IF NOT EXISTS idx_questions_type ON questions(type)",
+		"CREATE INDEX IF NOT EXISTS idx_questions_difficulty ON questions(difficulty)",
 		"CREATE INDEX IF NOT EXISTS idx_user_answers_user_id ON user_answers(user_id)",
 		"CREATE INDEX IF NOT EXISTS idx_user_answers_category ON user_answers(category)",
+		"CREATE INDEX IF NOT EXISTS idx_user_answers_question_id ON user_answers(question_id)",
 		"CREATE INDEX IF NOT EXISTS idx_exam_records_user_id ON exam_records(user_id)",
 	}
 	
@@ -113,6 +181,10 @@ type QuestionData struct {
 	Answer      string                 `json:"answer"`
 	Category    string                 `json:"category"`
 	Explanation string                 `json:"explanation,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Difficulty  int                    `json:"difficulty,omitempty"` // 1-5，0表示未设置
+	Grading     *models.GradingRule    `json:"grading,omitempty"`    // 填空题和简答题的判分规则
+	Attachments []AttachmentData       `json:"attachments,omitempty"` // 题目引用的附件
 }
 
 // importQuestionsFromJSON 从JSON文件导入题库数据
@@ -138,55 +210,193 @@ func importQuestionsFromJSON() error {
 	
 	log.Printf("Parsed question file: %s, total questions: %d", questionFile.Title, len(questionFile.Questions))
 	
-	// 批量插入数据
-	batchSize := 100
-	questionsData := questionFile.Questions
-	
-	for i := 0; i < len(questionsData); i += batchSize {
-		end := i + batchSize
-		if end > len(questionsData) {
-			end = len(questionsData)
+	_, err = ImportQuestions(questionFile.Questions, false, 0)
+	return err
+}
+
+// ImportResult 导入结果统计
+type ImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// ErrInvalidImportData 导入的题目数据不合法
+var ErrInvalidImportData = errors.New("invalid question data")
+
+// IsImportDataError 导入失败是否由题库文件内容（题目数据或附带附件）引起，而非服务端错误
+func IsImportDataError(err error) bool {
+	for _, target := range []error{ErrInvalidImportData, ErrAttachmentChecksum, ErrAttachmentMissing,
+		ErrAttachmentTooLarge, ErrUnsupportedAttachment} {
+		if errors.Is(err, target) {
+			return true
 		}
-		
-		batch := questionsData[i:end]
-		questions := make([]models.Question, len(batch))
-		
-		for j, qd := range batch {
-			// 转换选项为JSON字符串
-			var optionsJSON string
-			if len(qd.Options) > 0 {
-				// 将map转换为数组格式，并按键排序
-				var keys []string
-				for key := range qd.Options {
-					keys = append(keys, key)
-				}
-				sort.Strings(keys)
-				
-				var optionsList []string
-				for _, key := range keys {
-					optionsList = append(optionsList, fmt.Sprintf("%s. %s", key, qd.Options[key]))
-				}
-				optionsBytes, _ := json.Marshal(optionsList)
-				optionsJSON = string(optionsBytes)
+	}
+	return false
+}
+
+// ImportQuestions 导入题目数据，整个导入在一个事务中完成，失败时不保留任何修改
+// updateExisting为true时，ID已存在的题目按编辑处理并生成新版本，其余题目新建
+func ImportQuestions(questionsData []QuestionData, updateExisting bool, editorID uint) (*ImportResult, error) {
+	for i, qd := range questionsData {
+		if strings.TrimSpace(qd.Question) == "" || strings.TrimSpace(qd.Answer) == "" {
+			return nil, fmt.Errorf("question #%d: %w: question text and answer are required", i+1, ErrInvalidImportData)
+		}
+		if qd.Difficulty != 0 && !validDifficulty(qd.Difficulty) {
+			return nil, fmt.Errorf("question #%d: %w: %v", i+1, ErrInvalidImportData, ErrInvalidDifficulty)
+		}
+		qType := normalizeQuestionType(qd.Type)
+		if _, err := prepareGradingRule(qType, qd.Answer, qd.Grading); err != nil {
+			return nil, fmt.Errorf("question #%d: %w: %v", i+1, ErrInvalidImportData, err)
+		}
+		if err := validateStructuredAnswer(qType, qd.Options, qd.Answer); err != nil {
+			return nil, fmt.Errorf("question #%d: %w: %v", i+1, ErrInvalidImportData, err)
+		}
+	}
+
+	result := &ImportResult{}
+	var updatedIDs []uint
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 先保存随题库附带的附件，再确认引用的附件都已存在
+		if err := saveImportedAttachments(tx, questionsData, editorID); err != nil {
+			return err
+		}
+		for i := range questionsData {
+			if err := checkAttachmentRefs(tx, questionDataAttachmentRefs(&questionsData[i])); err != nil {
+				return fmt.Errorf("question #%d: %w", i+1, err)
 			}
-			
-			questions[j] = models.Question{
-				Type:        normalizeQuestionType(qd.Type),
-				Question:    strings.TrimSpace(qd.Question),
-				Options:     optionsJSON,
-				Answer:      strings.TrimSpace(qd.Answer),
-				Category:    strings.TrimSpace(qd.Category),
-				Explanation: strings.TrimSpace(qd.Explanation),
-				CreatedAt:   time.Now(),
+		}
+
+		var newQuestions []QuestionData
+		for _, qd := range questionsData {
+			if !updateExisting || qd.ID <= 0 {
+				// 追加导入时忽略文件中的ID，由数据库分配
+				qd.ID = 0
+				newQuestions = append(newQuestions, qd)
+				continue
+			}
+
+			// 库中不存在的ID保留原值，保证导出再导入后题目ID不变
+			var count int64
+			if err := tx.Model(&models.Question{}).Where("id = ?", qd.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				newQuestions = append(newQuestions, qd)
+				continue
+			}
+
+			// 每道题在保存点内编辑，没有变化时只回滚该题
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				_, _, err := updateQuestion(tx, uint(qd.ID), questionDataToUpdate(qd), editorID)
+				return err
+			})
+			switch {
+			case err == nil:
+				result.Updated++
+				updatedIDs = append(updatedIDs, uint(qd.ID))
+			case errors.Is(err, ErrNoQuestionChanges):
+				result.Unchanged++
+			default:
+				return fmt.Errorf("failed to update question %d: %w", qd.ID, err)
 			}
 		}
-		
-		if err := DB.Create(&questions).Error; err != nil {
-			return fmt.Errorf("failed to insert questions batch: %v", err)
+
+		// 批量插入数据
+		batchSize := 100
+
+		for i := 0; i < len(newQuestions); i += batchSize {
+			end := i + batchSize
+			if end > len(newQuestions) {
+				end = len(newQuestions)
+			}
+
+			batch := newQuestions[i:end]
+			questions := make([]models.Question, len(batch))
+
+			for j, qd := range batch {
+				qType := normalizeQuestionType(qd.Type)
+				grading, _ := prepareGradingRule(qType, qd.Answer, qd.Grading)
+				questions[j] = models.Question{
+					ID:          uint(qd.ID),
+					Type:        qType,
+					Question:    strings.TrimSpace(qd.Question),
+					Options:     formatOptions(qd.Options),
+					Answer:      strings.TrimSpace(qd.Answer),
+					Category:    strings.TrimSpace(qd.Category),
+					Explanation: strings.TrimSpace(qd.Explanation),
+					Tags:        NormalizeTags(qd.Tags),
+					Difficulty:  qd.Difficulty,
+					Grading:     grading,
+					CreatedAt:   time.Now(),
+				}
+			}
+
+			if err := tx.Create(&questions).Error; err != nil {
+				return fmt.Errorf("failed to insert questions batch: %v", err)
+			}
+			for i := range questions {
+				if err := saveQuestionTags(tx, questions[i].ID, questions[i].Tags); err != nil {
+					return err
+				}
+				if err := indexQuestion(tx, &questions[i]); err != nil {
+					return err
+				}
+			}
+			result.Created += len(questions)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	
-	return nil
+
+	for _, id := range updatedIDs {
+		Cache.InvalidateQuestion(id)
+	}
+	if result.Created > 0 || result.Updated > 0 {
+		InvalidateDuplicateClusters()
+		InvalidateCategoryTree()
+	}
+
+	return result, nil
+}
+
+// questionDataToUpdate 将导入的题目数据转换为全字段编辑请求
+func questionDataToUpdate(qd QuestionData) *models.QuestionUpdateRequest {
+	options := qd.Options
+	if options == nil {
+		options = map[string]string{}
+	}
+	req := &models.QuestionUpdateRequest{
+		Type:        &qd.Type,
+		Question:    &qd.Question,
+		Options:     options,
+		Answer:      &qd.Answer,
+		Category:    &qd.Category,
+		Explanation: &qd.Explanation,
+		Tags:        qd.Tags,    // 文件中未提供标签时保持不变
+		Grading:     qd.Grading, // 未提供判分规则时保持不变
+		ChangeNote:  "导入更新",
+	}
+	if qd.Difficulty != 0 {
+		req.Difficulty = &qd.Difficulty
+	}
+	return req
+}
+
+// formatOptions 将选项map转换为按键排序的JSON数组字符串，如 ["A. xxx","B. yyy"]
+func formatOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return ""
+	}
+
+	var optionsList []string
+	for _, key := range sortedKeys(options) {
+		optionsList = append(optionsList, fmt.Sprintf("%s. %s", key, strings.TrimSpace(options[key])))
+	}
+	optionsBytes, _ := json.Marshal(optionsList)
+	return string(optionsBytes)
 }
 
 // normalizeQuestionType 标准化题目类型
@@ -199,6 +409,14 @@ func normalizeQuestionType(qType string) string {
 		return "multiple"
 	case "判断", "判断题", "judge", "true_false":
 		return "judge"
+	case "填空", "填空题", "fill", "fill_blank", "blank":
+		return "fill"
+	case "简答", "简答题", "short", "short_answer", "essay":
+		return "short"
+	case "排序", "排序题", "ordering", "order", "sequence":
+		return "ordering"
+	case "匹配", "匹配题", "连线", "连线题", "matching", "match":
+		return "matching"
 	default:
 		return "single" // 默认为单选题
 	}
@@ -227,19 +445,37 @@ func GetQuestionStats() (*models.QuestionStats, error) {
 
 // GetUserStats 获取用户统计信息
 func GetUserStats(userID uint) (*models.UserStats, error) {
+	return GetUserStatsInCategory(userID, "")
+}
+
+// GetUserStatsInCategory 获取用户在指定分类（含分类树子节点）下的统计信息，category为空时统计全部
+func GetUserStatsInCategory(userID uint, category string) (*models.UserStats, error) {
 	var totalAnswered int64
 	var correctCount int64
+	var pendingReview int64
+	
+	// 等待人工复核的答题尚无结果，单独计数，不计入其余统计
+	if err := DB.Model(&models.UserAnswer{}).
+		Scopes(CategoryScope("category", category)).
+		Where("user_id = ? AND review_status = ?", userID, ReviewStatusPending).
+		Count(&pendingReview).Error; err != nil {
+		return nil, err
+	}
 	
 	// 获取总答题数
 	if err := DB.Model(&models.UserAnswer{}).
+		Scopes(CategoryScope("category", category)).
 		Where("user_id = ?", userID).
+		Where("review_status <> ?", ReviewStatusPending).
 		Count(&totalAnswered).Error; err != nil {
 		return nil, err
 	}
 	
 	// 获取正确答题数
 	if err := DB.Model(&models.UserAnswer{}).
+		Scopes(CategoryScope("category", category)).
 		Where("user_id = ? AND is_correct = ?", userID, true).
+		Where("review_status <> ?", ReviewStatusPending).
 		Count(&correctCount).Error; err != nil {
 		return nil, err
 	}
@@ -254,28 +490,38 @@ func GetUserStats(userID uint) (*models.UserStats, error) {
 	var categoryStats []models.CategoryStats
 	if err := DB.Model(&models.UserAnswer{}).
 		Select("category, count(*) as total, sum(case when is_correct then 1 else 0 end) as correct").
+		Scopes(CategoryScope("category", category)).
 		Where("user_id = ?", userID).
+		Where("review_status <> ?", ReviewStatusPending).
 		Group("category").
 		Find(&categoryStats).Error; err != nil {
 		return nil, err
 	}
 	
-	// 计算每个分类的准确率
+	// 计算每个分类的准确率和掌握程度
+	masteries, err := GetCategoryMastery(userID, category)
+	if err != nil {
+		return nil, err
+	}
 	for i := range categoryStats {
 		if categoryStats[i].Total > 0 {
 			categoryStats[i].Accuracy = float64(categoryStats[i].Correct) / float64(categoryStats[i].Total) * 100
 		}
+		if mastery, ok := masteries[categoryStats[i].Category]; ok {
+			categoryStats[i].Mastery = mastery.PKnown * 100
+			categoryStats[i].Mastered = mastery.PKnown >= MasteredThreshold
+		}
 	}
 	
-	// 获取错题
-	var wrongQuestions []models.WrongQuestion
-	if err := DB.Table("user_answers ua").
-		Select("ua.question_id, q.question, ua.user_answer, q.answer as correct_answer, ua.category, ua.answered_at").
-		Joins("JOIN questions q ON ua.question_id = q.id").
-		Where("ua.user_id = ? AND ua.is_correct = ?", userID, false).
-		Order("ua.answered_at DESC").
-		Limit(50). // 限制返回最近50道错题
-		Find(&wrongQuestions).Error; err != nil {
+	// 获取错题本中最近答错的50道题
+	wrongQuestions, _, err := GetWrongBook(userID, category, WrongStatusActive, 50, 0)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 题库覆盖情况
+	coverage, err := GetCoverage(userID, category)
+	if err != nil {
 		return nil, err
 	}
 	
@@ -285,5 +531,7 @@ func GetUserStats(userID uint) (*models.UserStats, error) {
 		Accuracy:       accuracy,
 		CategoryStats:  categoryStats,
 		WrongQuestions: wrongQuestions,
+		PendingReview:  int(pendingReview),
+		Coverage:       coverage,
 	}, nil
 }
\ No newline at end of file
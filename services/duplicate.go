@@ -0,0 +1,409 @@
+package services
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"quiz-system/models"
+)
+
+// 近似重复检测：题干与选项归一化后切分为字符n-gram，
+// 用MinHash签名和LSH分桶找出候选题目对，再按精确Jaccard相似度确认并聚类
+
+// DefaultDuplicateThreshold 默认相似度阈值，随机组卷去重使用该阈值下的聚类结果
+const DefaultDuplicateThreshold = 0.8
+
+// MinDuplicateThreshold 阈值下限，过低时LSH分桶召回率下降且结果失去意义
+const MinDuplicateThreshold = 0.5
+
+const (
+	shingleSize = 3   // 字符n-gram长度，中文不分词，按字符切分
+	minHashSize = 128 // MinHash签名长度
+	lshBands    = 32  // LSH分桶数，每桶 minHashSize/lshBands 个签名值
+)
+
+// ErrInvalidThreshold 相似度阈值超出范围
+var ErrInvalidThreshold = errors.New("threshold must be between 0.5 and 1")
+
+// optionLabelPattern 选项开头的字母序号，如 "A. "、"B、"、"C．"
+var optionLabelPattern = regexp.MustCompile(`^[A-Za-z]\s*[.．、:：)）]\s*`)
+
+// minHashSeeds MinHash的各个哈希种子，固定生成以保证结果可复现
+var minHashSeeds = func() [minHashSize]uint64 {
+	var seeds [minHashSize]uint64
+	state := uint64(0x5eed)
+	for i := range seeds {
+		state = splitMix64(state)
+		seeds[i] = state
+	}
+	return seeds
+}()
+
+// duplicateIndex 一次聚类的结果
+type duplicateIndex struct {
+	clusters  []models.DuplicateCluster
+	clusterOf map[uint]int // 题目ID -> 簇ID
+}
+
+var (
+	duplicateMu    sync.Mutex
+	duplicateCache *duplicateIndex // 默认阈值下的聚类结果，题目变更后失效
+)
+
+// InvalidateDuplicateClusters 题目新增或编辑后清除聚类结果，下次使用时重新计算
+func InvalidateDuplicateClusters() {
+	duplicateMu.Lock()
+	duplicateCache = nil
+	duplicateMu.Unlock()
+}
+
+// DuplicateClusterIndex 获取默认阈值下题目ID到近似重复簇ID的映射，不在任何簇中的题目不出现
+func DuplicateClusterIndex() (map[uint]int, error) {
+	index, err := defaultDuplicateIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.clusterOf, nil
+}
+
+// GetDuplicateClusters 获取近似重复题目簇，按簇大小和最高相似度排序
+// threshold为0时使用默认阈值；category不为空时只返回包含该分类（含子分类）题目的簇
+func GetDuplicateClusters(threshold float64, category string) ([]models.DuplicateCluster, error) {
+	if threshold == 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+	if threshold < MinDuplicateThreshold || threshold > 1 {
+		return nil, ErrInvalidThreshold
+	}
+
+	var index *duplicateIndex
+	var err error
+	if threshold == DefaultDuplicateThreshold {
+		index, err = defaultDuplicateIndex()
+	} else {
+		index, err = loadDuplicateIndex(threshold)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if category == "" {
+		return index.clusters, nil
+	}
+
+	categories, err := ExpandCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	inCategory := make(map[string]bool, len(categories))
+	for _, name := range categories {
+		inCategory[name] = true
+	}
+
+	var clusters []models.DuplicateCluster
+	for _, cluster := range index.clusters {
+		for _, member := range cluster.Questions {
+			if inCategory[member.Category] {
+				clusters = append(clusters, cluster)
+				break
+			}
+		}
+	}
+	return clusters, nil
+}
+
+// defaultDuplicateIndex 获取默认阈值下的聚类结果，未计算时计算并缓存
+func defaultDuplicateIndex() (*duplicateIndex, error) {
+	duplicateMu.Lock()
+	defer duplicateMu.Unlock()
+
+	if duplicateCache != nil {
+		return duplicateCache, nil
+	}
+	index, err := loadDuplicateIndex(DefaultDuplicateThreshold)
+	if err != nil {
+		return nil, err
+	}
+	duplicateCache = index
+	return index, nil
+}
+
+// loadDuplicateIndex 读取全部题目并聚类
+func loadDuplicateIndex(threshold float64) (*duplicateIndex, error) {
+	var questions []models.Question
+	if err := DB.Select("id", "type", "question", "options", "answer", "category").
+		Order("id").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return buildDuplicateIndex(questions, threshold), nil
+}
+
+// buildDuplicateIndex 对题目进行近似重复聚类
+func buildDuplicateIndex(questions []models.Question, threshold float64) *duplicateIndex {
+	shingles := make([][]uint64, len(questions))
+	signatures := make([][minHashSize]uint64, len(questions))
+	for i := range questions {
+		shingles[i] = shingleSet(duplicateText(&questions[i]))
+		signatures[i] = minHashSignature(shingles[i])
+	}
+
+	// LSH分桶：任一桶内签名完全相同的题目成为候选对
+	rows := minHashSize / lshBands
+	type pairKey struct{ a, b int }
+	candidates := make(map[pairKey]bool)
+	for band := 0; band < lshBands; band++ {
+		buckets := make(map[uint64][]int)
+		for i := range questions {
+			if len(shingles[i]) == 0 {
+				continue
+			}
+			h := fnv.New64a()
+			var buf [8]byte
+			for _, v := range signatures[i][band*rows : (band+1)*rows] {
+				binary.LittleEndian.PutUint64(buf[:], v)
+				h.Write(buf[:])
+			}
+			key := h.Sum64()
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, members := range buckets {
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					candidates[pairKey{members[x], members[y]}] = true
+				}
+			}
+		}
+	}
+
+	// 按精确Jaccard相似度确认候选对，并用并查集聚类
+	parent := make([]int, len(questions))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var pairs []models.DuplicatePair
+	var pairOwners []int
+	for pair := range candidates {
+		similarity := jaccard(shingles[pair.a], shingles[pair.b])
+		if similarity < threshold {
+			continue
+		}
+		pairs = append(pairs, models.DuplicatePair{
+			QuestionID:      questions[pair.a].ID,
+			OtherQuestionID: questions[pair.b].ID,
+			Similarity:      math.Round(similarity*1000) / 1000,
+		})
+		pairOwners = append(pairOwners, pair.a)
+		if ra, rb := find(pair.a), find(pair.b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	groups := make(map[int]*models.DuplicateCluster)
+	for i, pair := range pairs {
+		root := find(pairOwners[i])
+		cluster, ok := groups[root]
+		if !ok {
+			cluster = &models.DuplicateCluster{MinSimilarity: 1}
+			groups[root] = cluster
+		}
+		cluster.Pairs = append(cluster.Pairs, pair)
+		cluster.MaxSimilarity = math.Max(cluster.MaxSimilarity, pair.Similarity)
+		cluster.MinSimilarity = math.Min(cluster.MinSimilarity, pair.Similarity)
+	}
+	for i := range questions {
+		if cluster, ok := groups[find(i)]; ok {
+			q := &questions[i]
+			cluster.Questions = append(cluster.Questions, models.DuplicateMember{
+				ID:       q.ID,
+				Type:     q.Type,
+				Question: q.Question,
+				Options:  q.Options,
+				Answer:   q.Answer,
+				Category: q.Category,
+			})
+		}
+	}
+
+	clusters := make([]models.DuplicateCluster, 0, len(groups))
+	for _, cluster := range groups {
+		cluster.Size = len(cluster.Questions)
+		sort.Slice(cluster.Pairs, func(i, j int) bool {
+			if cluster.Pairs[i].Similarity != cluster.Pairs[j].Similarity {
+				return cluster.Pairs[i].Similarity > cluster.Pairs[j].Similarity
+			}
+			return cluster.Pairs[i].QuestionID < cluster.Pairs[j].QuestionID
+		})
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Size != clusters[j].Size {
+			return clusters[i].Size > clusters[j].Size
+		}
+		if clusters[i].MaxSimilarity != clusters[j].MaxSimilarity {
+			return clusters[i].MaxSimilarity > clusters[j].MaxSimilarity
+		}
+		return clusters[i].Questions[0].ID < clusters[j].Questions[0].ID
+	})
+
+	index := &duplicateIndex{clusters: clusters, clusterOf: make(map[uint]int)}
+	for i := range clusters {
+		clusters[i].ID = i + 1
+		for _, member := range clusters[i].Questions {
+			index.clusterOf[member.ID] = clusters[i].ID
+		}
+	}
+	return index
+}
+
+// duplicateText 生成用于比较的归一化文本
+// 选项去掉字母序号后排序，使仅选项顺序不同的题目文本一致；判断题选项固定，不参与比较
+func duplicateText(q *models.Question) string {
+	text := normalizeDuplicateText(q.Question)
+	if q.Type == "judge" || q.Options == "" {
+		return text
+	}
+
+	var options []string
+	if err := json.Unmarshal([]byte(q.Options), &options); err != nil {
+		return text
+	}
+	normalized := make([]string, 0, len(options))
+	for _, option := range options {
+		option = optionLabelPattern.ReplaceAllString(strings.TrimSpace(option), "")
+		if option = normalizeDuplicateText(option); option != "" {
+			normalized = append(normalized, option)
+		}
+	}
+	sort.Strings(normalized)
+	return text + "|" + strings.Join(normalized, "|")
+}
+
+// normalizeDuplicateText 全角转半角、转小写，去掉空白、标点和符号
+func normalizeDuplicateText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		r = foldWidth(r)
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// shingleSet 将文本切分为字符n-gram并返回排序去重后的哈希值
+func shingleSet(text string) []uint64 {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) < shingleSize {
+		return []uint64{hashShingle(string(runes))}
+	}
+
+	seen := make(map[uint64]bool, len(runes))
+	shingles := make([]uint64, 0, len(runes))
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		h := hashShingle(string(runes[i : i+shingleSize]))
+		if !seen[h] {
+			seen[h] = true
+			shingles = append(shingles, h)
+		}
+	}
+	sort.Slice(shingles, func(i, j int) bool { return shingles[i] < shingles[j] })
+	return shingles
+}
+
+// hashShingle 计算n-gram的64位哈希
+func hashShingle(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// minHashSignature 计算MinHash签名，每个种子对应一个哈希函数
+func minHashSignature(shingles []uint64) [minHashSize]uint64 {
+	var signature [minHashSize]uint64
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for _, shingle := range shingles {
+		for i, seed := range minHashSeeds {
+			if h := splitMix64(shingle ^ seed); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// jaccard 计算两个有序集合的Jaccard相似度
+func jaccard(a, b []uint64) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			intersection++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// splitMix64 64位整数混合函数
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// distinctClusterQuestions 每个近似重复簇至多保留一道题，与已排除题目同簇的题目也不保留
+func distinctClusterQuestions(questions []models.Question, clusterOf map[uint]int, excludeIDs []uint, limit int) []models.Question {
+	used := make(map[int]bool)
+	for _, id := range excludeIDs {
+		if cluster, ok := clusterOf[id]; ok {
+			used[cluster] = true
+		}
+	}
+
+	result := questions[:0]
+	for _, q := range questions {
+		if cluster, ok := clusterOf[q.ID]; ok {
+			if used[cluster] {
+				continue
+			}
+			used[cluster] = true
+		}
+		result = append(result, q)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// maxExplanationLength 社区解析的最大字数
+const maxExplanationLength = 5000
+
+// 社区解析状态
+const (
+	ExplanationVisible  = "visible"
+	ExplanationPromoted = "promoted"
+	ExplanationHidden   = "hidden"
+)
+
+// 社区解析相关错误
+var (
+	ErrEmptyExplanation     = errors.New("explanation cannot be empty")
+	ErrExplanationTooLong   = fmt.Errorf("explanation cannot exceed %d characters", maxExplanationLength)
+	ErrInvalidVote          = errors.New("vote value must be 1, -1 or 0")
+	ErrSelfVote             = errors.New("cannot vote on your own explanation")
+	ErrNotExplanationAuthor = errors.New("only the author or an admin can remove this explanation")
+)
+
+// SubmitExplanation 提交社区解析
+// 每位用户对每道题保留一条可见解析，再次提交时更新内容并清空已有投票
+func SubmitExplanation(userID, questionID uint, content string) (*models.CommunityExplanation, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyExplanation
+	}
+	if utf8.RuneCountInString(content) > maxExplanationLength {
+		return nil, ErrExplanationTooLong
+	}
+
+	if _, err := Cache.GetQuestion(questionID); err != nil {
+		return nil, err
+	}
+
+	var explanation models.CommunityExplanation
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND question_id = ? AND status = ?", userID, questionID, ExplanationVisible).
+			First(&explanation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			explanation = models.CommunityExplanation{
+				QuestionID: questionID,
+				UserID:     userID,
+				Content:    content,
+				Status:     ExplanationVisible,
+			}
+			return tx.Create(&explanation).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("explanation_id = ?", explanation.ID).Delete(&models.ExplanationVote{}).Error; err != nil {
+			return err
+		}
+		explanation.Content = content
+		explanation.Upvotes, explanation.Downvotes, explanation.Score = 0, 0, 0
+		return tx.Save(&explanation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &explanation, nil
+}
+
+// GetQuestionExplanations 获取题目的社区解析（不含已隐藏的），按得分排序
+func GetQuestionExplanations(questionID, userID uint) ([]models.CommunityExplanationView, error) {
+	var explanations []models.CommunityExplanationView
+	if err := DB.Table("community_explanations ce").
+		Select("ce.*, u.username, COALESCE(v.value, 0) as my_vote").
+		Joins("LEFT JOIN users u ON u.id = ce.user_id").
+		Joins("LEFT JOIN explanation_votes v ON v.explanation_id = ce.id AND v.user_id = ?", userID).
+		Where("ce.question_id = ? AND ce.status <> ?", questionID, ExplanationHidden).
+		Order("ce.score DESC, ce.created_at").
+		Find(&explanations).Error; err != nil {
+		return nil, err
+	}
+	return explanations, nil
+}
+
+// VoteExplanation 对社区解析投票，value为0时撤销投票
+func VoteExplanation(userID, explanationID uint, value int) (*models.CommunityExplanation, error) {
+	if value < -1 || value > 1 {
+		return nil, ErrInvalidVote
+	}
+
+	var explanation models.CommunityExplanation
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND status <> ?", explanationID, ExplanationHidden).
+			First(&explanation).Error; err != nil {
+			return err
+		}
+		if explanation.UserID == userID {
+			return ErrSelfVote
+		}
+
+		if err := tx.Where("explanation_id = ? AND user_id = ?", explanationID, userID).
+			Delete(&models.ExplanationVote{}).Error; err != nil {
+			return err
+		}
+		if value != 0 {
+			vote := models.ExplanationVote{ExplanationID: explanationID, UserID: userID, Value: value}
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+		}
+
+		// 按投票记录重新计算，避免并发投票导致计数偏差
+		var counts struct {
+			Upvotes   int
+			Downvotes int
+		}
+		if err := tx.Model(&models.ExplanationVote{}).
+			Select("COALESCE(SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END), 0) as upvotes, "+
+				"COALESCE(SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END), 0) as downvotes").
+			Where("explanation_id = ?", explanationID).
+			Scan(&counts).Error; err != nil {
+			return err
+		}
+		explanation.Upvotes = counts.Upvotes
+		explanation.Downvotes = counts.Downvotes
+		explanation.Score = counts.Upvotes - counts.Downvotes
+		return tx.Model(&explanation).
+			Select("upvotes", "downvotes", "score").
+			Updates(&explanation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &explanation, nil
+}
+
+// TopCommunityExplanation 获取得分最高且未被反对的社区解析，没有时返回nil
+func TopCommunityExplanation(questionID uint) (*models.CommunityExplanation, error) {
+	var explanations []models.CommunityExplanation
+	if err := DB.Where("question_id = ? AND status = ? AND score >= 0", questionID, ExplanationVisible).
+		Order("score DESC, created_at").
+		Limit(1).
+		Find(&explanations).Error; err != nil {
+		return nil, err
+	}
+	if len(explanations) == 0 {
+		return nil, nil
+	}
+	return &explanations[0], nil
+}
+
+// ListExplanationCandidates 获取缺少官方解析的题目的社区解析，供管理员挑选采纳
+func ListExplanationCandidates(minScore, limit, offset int) ([]models.CommunityExplanationView, error) {
+	var explanations []models.CommunityExplanationView
+	if err := DB.Table("community_explanations ce").
+		Select("ce.*, u.username").
+		Joins("JOIN questions q ON q.id = ce.question_id").
+		Joins("LEFT JOIN users u ON u.id = ce.user_id").
+		Where("ce.status = ? AND ce.score >= ?", ExplanationVisible, minScore).
+		Where("q.explanation IS NULL OR q.explanation = ''").
+		Order("ce.score DESC, ce.created_at").
+		Limit(limit).
+		Offset(offset).
+		Find(&explanations).Error; err != nil {
+		return nil, err
+	}
+	return explanations, nil
+}
+
+// PromoteExplanation 将社区解析采纳为题目的官方解析（生成新版本），并通知作者
+func PromoteExplanation(explanationID, adminID uint) (*models.CommunityExplanation, *models.QuestionRevision, error) {
+	var explanation models.CommunityExplanation
+	if err := DB.Where("id = ? AND status <> ?", explanationID, ExplanationHidden).
+		First(&explanation).Error; err != nil {
+		return nil, nil, err
+	}
+
+	_, revision, err := UpdateQuestion(explanation.QuestionID, &models.QuestionUpdateRequest{
+		Explanation: &explanation.Content,
+		ChangeNote:  fmt.Sprintf("采纳社区解析 #%d", explanation.ID),
+	}, adminID)
+	if err != nil && !errors.Is(err, ErrNoQuestionChanges) {
+		return nil, nil, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if explanation.Status == ExplanationPromoted {
+			return nil
+		}
+		explanation.Status = ExplanationPromoted
+		if err := tx.Model(&explanation).Update("status", ExplanationPromoted).Error; err != nil {
+			return err
+		}
+		return CreateNotification(tx, &models.Notification{
+			UserID:     explanation.UserID,
+			Type:       "explanation_promoted",
+			Title:      "解析已被采纳",
+			Message:    fmt.Sprintf("你为题目 #%d 提交的解析已被采纳为官方解析。", explanation.QuestionID),
+			QuestionID: explanation.QuestionID,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &explanation, revision, nil
+}
+
+// HideExplanation 隐藏社区解析，仅作者本人或管理员可操作
+func HideExplanation(explanationID, userID uint, isAdmin bool) error {
+	var explanation models.CommunityExplanation
+	if err := DB.Where("id = ? AND status <> ?", explanationID, ExplanationHidden).
+		First(&explanation).Error; err != nil {
+		return err
+	}
+	if explanation.UserID != userID && !isAdmin {
+		return ErrNotExplanationAuthor
+	}
+	return DB.Model(&explanation).Update("status", ExplanationHidden).Error
+}
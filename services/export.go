@@ -0,0 +1,332 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+)
+
+// 支持的导出格式
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatQTI      = "qti"
+	FormatGIFT     = "gift"
+	FormatAiken    = "aiken"
+	FormatBundle   = "bundle"
+)
+
+// csvOptionKeys CSV中固定的选项列，数字列为匹配题的左侧项
+var csvOptionKeys = []string{"A", "B", "C", "D", "E", "F", "G", "H", "1", "2", "3", "4", "5", "6", "7", "8"}
+
+// questionTypeNames 题型中文名称
+var questionTypeNames = map[string]string{
+	"single":   "单选题",
+	"multiple": "多选题",
+	"judge":    "判断题",
+	"fill":     "填空题",
+	"short":    "简答题",
+	"ordering": "排序题",
+	"matching": "匹配题",
+}
+
+// ExportContentType 返回导出格式对应的Content-Type和文件扩展名
+func ExportContentType(format string) (string, string, error) {
+	switch format {
+	case FormatJSON:
+		return "application/json; charset=utf-8", "json", nil
+	case FormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8", "md", nil
+	case FormatQTI:
+		return "application/zip", "zip", nil
+	case FormatGIFT:
+		return "text/plain; charset=utf-8", "gift.txt", nil
+	case FormatAiken:
+		return "text/plain; charset=utf-8", "aiken.txt", nil
+	case FormatBundle:
+		return "application/zip", "bundle.zip", nil
+	}
+	return "", "", fmt.Errorf("unsupported export format: %s", format)
+}
+
+// ExportQuestions 按指定格式流式导出题库
+func ExportQuestions(w io.Writer, format string, filter QuestionFilter) error {
+	switch format {
+	case FormatJSON:
+		return exportJSON(w, filter)
+	case FormatCSV:
+		return exportCSV(w, filter)
+	case FormatMarkdown:
+		return exportMarkdown(w, filter)
+	case FormatQTI:
+		return exportQTI(w, filter)
+	case FormatGIFT:
+		return exportGIFT(w, filter)
+	case FormatAiken:
+		return exportAiken(w, filter)
+	case FormatBundle:
+		return exportBundle(w, filter)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// eachQuestion 分批遍历符合条件的题目，避免一次性加载整个题库
+func eachQuestion(filter QuestionFilter, order string, fn func(q *models.Question) error) error {
+	const batchSize = 200
+
+	// FindInBatches按主键翻页，不支持自定义排序，这里按偏移量分批
+	for offset := 0; ; offset += batchSize {
+		var questions []models.Question
+		if err := filter.apply(DB.Model(&models.Question{})).
+			Order(order).
+			Offset(offset).
+			Limit(batchSize).
+			Find(&questions).Error; err != nil {
+			return err
+		}
+
+		for i := range questions {
+			if err := fn(&questions[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(questions) < batchSize {
+			return nil
+		}
+	}
+}
+
+// exportJSON 导出为与questions.json相同的QuestionFile格式
+func exportJSON(w io.Writer, filter QuestionFilter) error {
+	var total int64
+	if err := filter.apply(DB.Model(&models.Question{})).Count(&total).Error; err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("{\n  \"title\": %s,\n  \"description\": %s,\n  \"total_questions\": %d,\n  \"questions\": [",
+		jsonString(exportTitle(filter)), jsonString("由刷题系统导出"), total)
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	first := true
+	err := eachQuestion(filter, "id", func(q *models.Question) error {
+		data, err := marshalIndent(QuestionToData(q), "    ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n    "
+		if first {
+			sep = "\n    "
+			first = false
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n  ]\n}\n")
+	return err
+}
+
+// exportCSV 导出为CSV，每个选项占一列
+func exportCSV(w io.Writer, filter QuestionFilter) error {
+	// 写入BOM，便于Excel正确识别UTF-8中文
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"id", "type", "category", "question"}, csvOptionKeys...)
+	header = append(header, "answer", "explanation", "tags", "difficulty", "grading")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	err := eachQuestion(filter, "id", func(q *models.Question) error {
+		data := QuestionToData(q)
+		for key := range data.Options {
+			if !isCSVOptionKey(key) {
+				return fmt.Errorf("question %d: option %q cannot be exported to CSV", data.ID, key)
+			}
+		}
+
+		record := []string{strconv.Itoa(data.ID), data.Type, data.Category, data.Question}
+		for _, key := range csvOptionKeys {
+			record = append(record, data.Options[key])
+		}
+		difficulty := ""
+		if data.Difficulty > 0 {
+			difficulty = strconv.Itoa(data.Difficulty)
+		}
+		grading := ""
+		if data.Grading != nil {
+			gradingJSON, err := json.Marshal(data.Grading)
+			if err != nil {
+				return err
+			}
+			grading = string(gradingJSON)
+		}
+		record = append(record, data.Answer, data.Explanation, strings.Join(data.Tags, ";"), difficulty, grading)
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportMarkdown 导出为按分类分组、附答案和解析的Markdown复习资料
+func exportMarkdown(w io.Writer, filter QuestionFilter) error {
+	if _, err := fmt.Fprintf(w, "# %s\n", exportTitle(filter)); err != nil {
+		return err
+	}
+
+	currentCategory := ""
+	number := 0
+	return eachQuestion(filter, "category, id", func(q *models.Question) error {
+		if q.Category != currentCategory {
+			currentCategory = q.Category
+			number = 0
+			if _, err := fmt.Fprintf(w, "\n## %s\n", currentCategory); err != nil {
+				return err
+			}
+		}
+		number++
+
+		var sb strings.Builder
+		typeName := questionTypeNames[q.Type]
+		if typeName == "" {
+			typeName = q.Type
+		}
+		fmt.Fprintf(&sb, "\n### %d. 【%s】%s\n\n", number, typeName, q.Question)
+		for _, option := range parseOptionList(q.Options) {
+			fmt.Fprintf(&sb, "- %s\n", option)
+		}
+		fmt.Fprintf(&sb, "\n**答案：** %s\n", q.Answer)
+		if q.Grading != nil && len(q.Grading.Keywords) > 0 {
+			fmt.Fprintf(&sb, "\n**评分关键词：** %s\n", strings.Join(q.Grading.Keywords, "、"))
+		}
+		if q.Explanation != "" {
+			fmt.Fprintf(&sb, "\n**解析：** %s\n", q.Explanation)
+		}
+		if len(q.Tags) > 0 {
+			fmt.Fprintf(&sb, "\n**标签：** %s\n", strings.Join(q.Tags, "、"))
+		}
+		if q.Difficulty > 0 {
+			fmt.Fprintf(&sb, "\n**难度：** %s\n", strings.Repeat("★", q.Difficulty))
+		}
+		fmt.Fprintf(&sb, "\n<sub>题目ID: %d · 版本: %d</sub>\n", q.ID, q.Revision)
+
+		_, err := io.WriteString(w, sb.String())
+		return err
+	})
+}
+
+// QuestionToData 将题目转换为题库文件中的QuestionData格式
+func QuestionToData(q *models.Question) QuestionData {
+	return QuestionData{
+		ID:          int(q.ID),
+		Type:        q.Type,
+		Question:    q.Question,
+		Options:     parseOptions(q.Options),
+		Answer:      q.Answer,
+		Category:    q.Category,
+		Explanation: q.Explanation,
+		Tags:        q.Tags,
+		Difficulty:  q.Difficulty,
+		Grading:     q.Grading,
+		Attachments: attachmentMetadata(questionAttachmentRefs(q)),
+	}
+}
+
+// parseOptionList 解析存储的选项JSON数组
+func parseOptionList(optionsJSON string) []string {
+	if optionsJSON == "" {
+		return nil
+	}
+	var options []string
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return nil
+	}
+	return options
+}
+
+// parseOptions 将 ["A. xxx","B. yyy"] 还原为选项map，与formatOptions互逆
+func parseOptions(optionsJSON string) map[string]string {
+	options := parseOptionList(optionsJSON)
+	if len(options) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(options))
+	for i, option := range options {
+		key, text, found := strings.Cut(option, ". ")
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			// 非标准格式时按顺序分配选项字母
+			key = string(rune('A' + i))
+			text = option
+		}
+		result[key] = text
+	}
+	return result
+}
+
+// isCSVOptionKey 判断选项键是否有对应的CSV列
+func isCSVOptionKey(key string) bool {
+	for _, k := range csvOptionKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// exportTitle 生成导出文件标题
+func exportTitle(filter QuestionFilter) string {
+	title := "密评考试题库"
+	if filter.Category != "" {
+		title += " - " + filter.Category
+	}
+	if typeName := questionTypeNames[filter.Type]; typeName != "" {
+		title += " - " + typeName
+	}
+	if len(filter.Tags) > 0 {
+		title += " - " + strings.Join(filter.Tags, "、")
+	}
+	return title
+}
+
+// jsonString 将字符串编码为JSON字符串字面量
+func jsonString(s string) string {
+	data, _ := marshalIndent(s, "")
+	return string(data)
+}
+
+// marshalIndent 与json.MarshalIndent相同，但不转义HTML字符，保持导出内容可读
+func marshalIndent(v interface{}, prefix string) ([]byte, error) {
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSuffix(buf.String(), "\n")), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxDifficulty 题目难度上限，难度取值1-5，0表示未设置
+const MaxDifficulty = 5
+
+// ErrInvalidDifficulty 难度超出范围
+var ErrInvalidDifficulty = errors.New("difficulty must be between 1 and 5")
+
+// QuestionFilter 题目筛选条件
+type QuestionFilter struct {
+	Category      string
+	Type          string
+	Tags          []string // 需同时包含的标签
+	MinDifficulty int
+	MaxDifficulty int
+	ExcludeIDs    []uint
+	// DistinctClusters 随机抽题时同一近似重复簇至多抽取一道，且不与ExcludeIDs中的题目同簇
+	DistinctClusters bool
+	// UnseenFirstUserID 随机抽题时优先抽取该用户从未作答过的题目，不足时再抽取作答过的，0为不区分
+	UnseenFirstUserID uint
+}
+
+// apply 将筛选条件应用到查询
+func (f QuestionFilter) apply(query *gorm.DB) *gorm.DB {
+	query = query.Scopes(CategoryScope("category", f.Category), TagScope("id", f.Tags))
+	if f.Type != "" {
+		query = query.Where("type = ?", f.Type)
+	}
+	if f.MinDifficulty > 0 {
+		query = query.Where("difficulty >= ?", f.MinDifficulty)
+	}
+	if f.MaxDifficulty > 0 {
+		query = query.Where("difficulty <= ?", f.MaxDifficulty)
+	}
+	if len(f.ExcludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", f.ExcludeIDs)
+	}
+	return query
+}
+
+// Scope 以gorm查询范围的形式使用筛选条件
+func (f QuestionFilter) Scope(query *gorm.DB) *gorm.DB {
+	return f.apply(query)
+}
+
+// ParseDifficultyRange 解析难度筛选参数，如 "3" 或 "2-4"，为空时不限
+func ParseDifficultyRange(s string) (int, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+
+	minStr, maxStr, isRange := strings.Cut(s, "-")
+	if !isRange {
+		maxStr = minStr
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(minStr))
+	if err != nil {
+		return 0, 0, ErrInvalidDifficulty
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(maxStr))
+	if err != nil {
+		return 0, 0, ErrInvalidDifficulty
+	}
+	if !validDifficulty(min) || !validDifficulty(max) || min > max {
+		return 0, 0, ErrInvalidDifficulty
+	}
+	return min, max, nil
+}
+
+// validDifficulty 难度是否在1-5之间
+func validDifficulty(difficulty int) bool {
+	return difficulty >= 1 && difficulty <= MaxDifficulty
+}
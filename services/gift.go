@@ -0,0 +1,580 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+)
+
+// giftDefaultCategory 未指定$CATEGORY时的分类
+const giftDefaultCategory = "GIFT导入"
+
+// giftTitleIDPattern 导出时写入的题目标题，导入时据此还原题目ID
+var giftTitleIDPattern = regexp.MustCompile(`^q(\d+)$`)
+
+// giftWeightPattern 多选题答案权重，如 %50% 或 %-100%
+var giftWeightPattern = regexp.MustCompile(`^%(-?[0-9.]+)%`)
+
+// giftSpecialChars GIFT中需要转义的字符
+var giftSpecialChars = strings.NewReplacer(
+	`\`, `\\`, `~`, `\~`, `=`, `\=`, `#`, `\#`, `{`, `\{`, `}`, `\}`, `:`, `\:`,
+)
+
+// giftAnswer 解析出的单个答案
+type giftAnswer struct {
+	Text     string
+	Correct  bool
+	Weighted bool // ~%权重%写法，Moodle中表示多选题
+	Feedback string
+}
+
+// parseGIFT 解析Moodle GIFT格式，支持单选、多选（%权重%）、判断题、简答题（导入为填空题）和匹配题
+func parseGIFT(r io.Reader) ([]QuestionData, error) {
+	blocks, err := splitGIFTBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	category := giftDefaultCategory
+	var questions []QuestionData
+	for _, block := range blocks {
+		if strings.HasPrefix(block.text, "$CATEGORY:") {
+			category = parseGIFTCategory(block.text)
+			continue
+		}
+
+		qd, err := parseGIFTQuestion(block.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", block.line, err)
+		}
+		if qd == nil {
+			log.Printf("Skipping unsupported GIFT question at line %d", block.line)
+			continue
+		}
+		qd.Category = category
+		questions = append(questions, *qd)
+	}
+
+	return questions, nil
+}
+
+type giftBlock struct {
+	text string
+	line int
+}
+
+// splitGIFTBlocks 按空行切分题目，去除注释行；花括号内的空行不作为分隔
+func splitGIFTBlocks(r io.Reader) ([]giftBlock, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var blocks []giftBlock
+	var current []string
+	start, lineNo, depth := 0, 0, 0
+
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, giftBlock{text: strings.TrimSpace(strings.Join(current, "\n")), line: start})
+			current = nil
+		}
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "//") && depth == 0 {
+			continue
+		}
+		if trimmed == "" && depth == 0 {
+			flush()
+			continue
+		}
+		// $CATEGORY 单独成块
+		if strings.HasPrefix(trimmed, "$CATEGORY:") && depth == 0 {
+			flush()
+			blocks = append(blocks, giftBlock{text: trimmed, line: lineNo})
+			continue
+		}
+
+		if len(current) == 0 {
+			start = lineNo
+		}
+		current = append(current, line)
+		depth += giftBraceDelta(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return blocks, nil
+}
+
+// giftBraceDelta 计算一行中未转义花括号的深度变化
+func giftBraceDelta(line string) int {
+	delta := 0
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '{':
+			delta++
+		case r == '}':
+			delta--
+		}
+	}
+	return delta
+}
+
+// parseGIFTCategory 解析 $CATEGORY: $course$/top/分类 形式的分类行
+func parseGIFTCategory(line string) string {
+	category := strings.TrimSpace(strings.TrimPrefix(line, "$CATEGORY:"))
+	category = strings.TrimPrefix(category, "$course$/")
+	category = strings.TrimPrefix(category, "top/")
+	if category == "" || category == "top" {
+		return giftDefaultCategory
+	}
+	return category
+}
+
+// parseGIFTQuestion 解析单道题目，不支持的题型返回nil
+func parseGIFTQuestion(text string) (*QuestionData, error) {
+	qd := &QuestionData{}
+
+	// 可选标题 ::title::
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated question title")
+		}
+		title := strings.TrimSpace(text[2 : 2+end])
+		if m := giftTitleIDPattern.FindStringSubmatch(title); m != nil {
+			qd.ID, _ = strconv.Atoi(m[1])
+		}
+		text = strings.TrimSpace(text[2+end+2:])
+	}
+
+	// 去除可选的文本格式标记，如 [html]、[markdown]
+	if strings.HasPrefix(text, "[") {
+		if end := strings.Index(text, "]"); end > 0 && !strings.ContainsAny(text[1:end], " \n") {
+			text = strings.TrimSpace(text[end+1:])
+		}
+	}
+
+	openIdx := indexUnescaped(text, "{")
+	if openIdx < 0 {
+		// 纯描述文本，没有答案
+		return nil, nil
+	}
+	closeRel := indexUnescaped(text[openIdx:], "}")
+	if closeRel < 0 {
+		return nil, fmt.Errorf("unterminated answer block")
+	}
+	closeIdx := openIdx + closeRel
+
+	before := strings.TrimSpace(text[:openIdx])
+	after := strings.TrimSpace(text[closeIdx+1:])
+	body := strings.TrimSpace(text[openIdx+1 : closeIdx])
+
+	// 答案块在题干中间时视为填空位置
+	question := before
+	if after != "" {
+		question = before + "（  ）" + after
+	}
+	qd.Question = unescapeGIFT(question)
+	if qd.Question == "" {
+		return nil, fmt.Errorf("empty question text")
+	}
+
+	// 通用反馈 ####
+	generalFeedback := ""
+	if idx := indexUnescaped(body, "####"); idx >= 0 {
+		generalFeedback = unescapeGIFT(strings.TrimSpace(body[idx+4:]))
+		body = strings.TrimSpace(body[:idx])
+	}
+
+	// 判断题 {T} {TRUE} {F} {FALSE}
+	head, feedback := body, ""
+	if idx := indexUnescaped(body, "#"); idx >= 0 {
+		head, feedback = strings.TrimSpace(body[:idx]), body[idx:]
+	}
+	switch strings.ToUpper(head) {
+	case "T", "TRUE", "F", "FALSE":
+		qd.Type = "judge"
+		qd.Options = map[string]string{"A": "正确", "B": "错误"}
+		qd.Answer = "A"
+		if strings.HasPrefix(strings.ToUpper(head), "F") {
+			qd.Answer = "B"
+		}
+		qd.Explanation = generalFeedback
+		if qd.Explanation == "" {
+			// {T#错误时反馈#正确时反馈}，取正确作答时的反馈作为解析
+			parts := splitUnescaped(strings.TrimPrefix(feedback, "#"), '#')
+			qd.Explanation = unescapeGIFT(strings.TrimSpace(parts[len(parts)-1]))
+		}
+		return qd, nil
+	}
+
+	answers := parseGIFTAnswers(body)
+	if !hasGIFTDistractor(answers) {
+		// 只有=答案的是简答题（导入为单空填空题）或匹配题（=a -> b）
+		if isGIFTMatching(answers) {
+			return parseGIFTMatching(qd, answers, generalFeedback), nil
+		}
+		return parseGIFTShortAnswer(qd, answers, generalFeedback), nil
+	}
+	if len(answers) < 2 {
+		return nil, nil
+	}
+
+	var correct, feedbacks []string
+	multiple := false
+	qd.Options = make(map[string]string, len(answers))
+	for i, answer := range answers {
+		multiple = multiple || answer.Weighted
+		letter := string(rune('A' + i))
+		qd.Options[letter] = answer.Text
+		if answer.Correct {
+			correct = append(correct, letter)
+			if answer.Feedback != "" {
+				feedbacks = append(feedbacks, answer.Feedback)
+			}
+		}
+	}
+	if len(correct) == 0 {
+		return nil, fmt.Errorf("question has no correct answer")
+	}
+
+	qd.Type = "single"
+	if multiple || len(correct) > 1 {
+		qd.Type = "multiple"
+	}
+	qd.Answer = strings.Join(correct, "")
+	qd.Explanation = generalFeedback
+	if qd.Explanation == "" {
+		qd.Explanation = strings.Join(feedbacks, "\n")
+	}
+	return qd, nil
+}
+
+// isGIFTMatching 答案均为 =左侧 -> 右侧 写法时为匹配题
+func isGIFTMatching(answers []giftAnswer) bool {
+	for _, answer := range answers {
+		if !strings.Contains(answer.Text, "->") {
+			return false
+		}
+	}
+	return len(answers) > 0
+}
+
+// parseGIFTMatching 将GIFT匹配题转换为匹配题，左侧项按顺序编号，右侧项去重后按首次出现顺序分配字母
+// 左侧为空的 = -> 右侧 写法为干扰项；没有有效配对时返回nil
+func parseGIFTMatching(qd *QuestionData, answers []giftAnswer, generalFeedback string) *QuestionData {
+	qd.Options = make(map[string]string)
+	letters := make(map[string]string)
+	var pairs []string
+	for _, answer := range answers {
+		left, right, _ := strings.Cut(answer.Text, "->")
+		left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+		if right == "" {
+			continue
+		}
+		letter, ok := letters[right]
+		if !ok {
+			if len(letters) == 26 {
+				return nil
+			}
+			letter = string(rune('A' + len(letters)))
+			letters[right] = letter
+			qd.Options[letter] = right
+		}
+		if left != "" {
+			number := strconv.Itoa(len(pairs) + 1)
+			qd.Options[number] = left
+			pairs = append(pairs, number+letter)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	qd.Type = "matching"
+	qd.Answer = strings.Join(pairs, ",")
+	qd.Explanation = generalFeedback
+	return qd
+}
+
+// parseGIFTShortAnswer 将GIFT简答题转换为单空填空题，所有=答案均为可接受答案
+func parseGIFTShortAnswer(qd *QuestionData, answers []giftAnswer, generalFeedback string) *QuestionData {
+	var accepted, feedbacks []string
+	for _, answer := range answers {
+		if strings.Contains(answer.Text, "->") {
+			return nil
+		}
+		if answer.Text == "" {
+			continue
+		}
+		accepted = append(accepted, answer.Text)
+		if answer.Feedback != "" {
+			feedbacks = append(feedbacks, answer.Feedback)
+		}
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	qd.Type = "fill"
+	qd.Answer = strings.Join(accepted, "|")
+	qd.Explanation = generalFeedback
+	if qd.Explanation == "" {
+		qd.Explanation = strings.Join(feedbacks, "\n")
+	}
+	return qd
+}
+
+// parseGIFTAnswers 解析答案块中的 =正确 和 ~错误/~%权重% 答案
+func parseGIFTAnswers(body string) []giftAnswer {
+	var answers []giftAnswer
+	var current *giftAnswer
+	var sb strings.Builder
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		raw := strings.TrimSpace(sb.String())
+		if m := giftWeightPattern.FindStringSubmatch(raw); m != nil {
+			weight, _ := strconv.ParseFloat(m[1], 64)
+			current.Weighted = !current.Correct && weight > 0
+			current.Correct = weight > 0
+			raw = strings.TrimSpace(raw[len(m[0]):])
+		}
+		if idx := indexUnescaped(raw, "#"); idx >= 0 {
+			current.Feedback = unescapeGIFT(strings.TrimSpace(raw[idx+1:]))
+			raw = strings.TrimSpace(raw[:idx])
+		}
+		current.Text = unescapeGIFT(raw)
+		answers = append(answers, *current)
+		sb.Reset()
+	}
+
+	escaped := false
+	for _, r := range body {
+		switch {
+		case escaped:
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '=' || r == '~':
+			finish()
+			current = &giftAnswer{Correct: r == '='}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	finish()
+
+	return answers
+}
+
+// hasGIFTDistractor 是否包含~开头的答案，选择题至少有一个
+func hasGIFTDistractor(answers []giftAnswer) bool {
+	for _, answer := range answers {
+		if !answer.Correct || answer.Weighted {
+			return true
+		}
+	}
+	return false
+}
+
+// indexUnescaped 查找未被反斜杠转义的子串
+func indexUnescaped(s, sub string) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitUnescaped 按未转义的分隔符切分
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeGIFT 还原转义字符，\n 转为换行
+func unescapeGIFT(s string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r == 'n' {
+				sb.WriteRune('\n')
+			} else {
+				sb.WriteRune(r)
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// escapeGIFT 转义特殊字符，换行写为 \n 以保持单行
+func escapeGIFT(s string) string {
+	return strings.ReplaceAll(giftSpecialChars.Replace(s), "\n", `\n`)
+}
+
+// exportGIFT 导出为Moodle GIFT格式，分类写为 $CATEGORY 行
+func exportGIFT(w io.Writer, filter QuestionFilter) error {
+	bw := bufio.NewWriter(w)
+	currentCategory := ""
+
+	err := eachQuestion(filter, "category, id", func(q *models.Question) error {
+		text, err := formatGIFTQuestion(q)
+		if err != nil {
+			log.Printf("Skipping question in GIFT export: %v", err)
+			return nil
+		}
+
+		if q.Category != currentCategory {
+			currentCategory = q.Category
+			fmt.Fprintf(bw, "$CATEGORY: $course$/top/%s\n\n", currentCategory)
+		}
+		_, err = bw.WriteString(text)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// formatGIFTQuestion 将题目格式化为GIFT文本
+func formatGIFTQuestion(q *models.Question) (string, error) {
+	options := parseOptions(q.Options)
+	correct := answerLetterSet(q.Answer)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// id:%d revision:%d\n", q.ID, q.Revision)
+	fmt.Fprintf(&sb, "::q%d:: %s {\n", q.ID, escapeGIFT(q.Question))
+
+	switch q.Type {
+	case "fill":
+		// GIFT简答题只有一个空，各可接受答案分别写为=答案
+		blanks := ParseFillAnswers(q.Answer)
+		if len(blanks) != 1 {
+			return "", fmt.Errorf("question %d: only single-blank fill questions can be exported to GIFT", q.ID)
+		}
+		for _, answer := range blanks[0] {
+			fmt.Fprintf(&sb, "\t=%s\n", escapeGIFT(answer))
+		}
+	case "short":
+		return "", fmt.Errorf("question %d: short answer questions cannot be exported to GIFT", q.ID)
+	case "ordering":
+		return "", fmt.Errorf("question %d: ordering questions cannot be exported to GIFT", q.ID)
+	case "matching":
+		// 左侧项按编号写为 =左侧 -> 右侧，未被配对的右侧项写为干扰项 = -> 右侧
+		pairs := ParseMatchingAnswer(q.Answer)
+		used := make(map[string]bool)
+		for _, key := range sortedKeys(options) {
+			if isOptionLetter(key) {
+				continue
+			}
+			letter := pairs[strings.TrimLeft(key, "0")]
+			if options[letter] == "" {
+				return "", fmt.Errorf("question %d has no valid answer for item %s", q.ID, key)
+			}
+			used[letter] = true
+			fmt.Fprintf(&sb, "\t=%s -> %s\n", escapeGIFT(options[key]), escapeGIFT(options[letter]))
+		}
+		for _, key := range sortedKeys(options) {
+			if isOptionLetter(key) && !used[key] {
+				fmt.Fprintf(&sb, "\t= -> %s\n", escapeGIFT(options[key]))
+			}
+		}
+	case "judge":
+		if len(correct) != 1 {
+			return "", fmt.Errorf("question %d has no valid answer", q.ID)
+		}
+		isTrue := false
+		for letter := range correct {
+			text := options[string(letter)]
+			isTrue = text == "正确" || text == "对" || strings.EqualFold(text, "true") || (text == "" && letter == 'A')
+		}
+		if isTrue {
+			sb.WriteString("\tTRUE\n")
+		} else {
+			sb.WriteString("\tFALSE\n")
+		}
+	default:
+		if len(options) == 0 || len(correct) == 0 {
+			return "", fmt.Errorf("question %d has no options or answer", q.ID)
+		}
+
+		for _, key := range sortedKeys(options) {
+			isCorrect := len(key) == 1 && correct[rune(key[0])]
+			text := escapeGIFT(options[key])
+			switch {
+			case q.Type == "multiple" && isCorrect:
+				fmt.Fprintf(&sb, "\t~%%%s%%%s\n", giftWeight(100/float64(len(correct))), text)
+			case q.Type == "multiple":
+				fmt.Fprintf(&sb, "\t~%%-100%%%s\n", text)
+			case isCorrect:
+				fmt.Fprintf(&sb, "\t=%s\n", text)
+			default:
+				fmt.Fprintf(&sb, "\t~%s\n", text)
+			}
+		}
+	}
+
+	if q.Explanation != "" {
+		fmt.Fprintf(&sb, "\t####%s\n", escapeGIFT(q.Explanation))
+	}
+	sb.WriteString("}\n\n")
+	return sb.String(), nil
+}
+
+// giftWeight 格式化答案权重，最多保留5位小数（Moodle的精度）
+func giftWeight(weight float64) string {
+	s := strconv.FormatFloat(weight, 'f', 5, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
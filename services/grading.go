@@ -0,0 +1,471 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"quiz-system/models"
+)
+
+// 人工复核状态，自动判分的答题记录为空
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusReviewed = "reviewed"
+)
+
+// DefaultPassScore 简答题关键词得分比例达到该值时自动判为正确
+const DefaultPassScore = 0.8
+
+// normalizeRuleNames 填空题可用的归一化规则：全半角、大小写、空白、标点符号
+var normalizeRuleNames = []string{"width", "case", "space", "punct"}
+
+// ErrInvalidGrading 判分规则或填空题答案格式无效
+var ErrInvalidGrading = errors.New("invalid grading rule")
+
+// GradeAnswer 按题目当前答案判分
+// 选择题和判断题只有对错；填空题按答对的空数、排序题按最长有序子序列、匹配题按配对正确数得部分分；
+// 简答题按关键词评分，无法自动判定时等待人工复核
+func GradeAnswer(question *models.Question, userAnswer string) models.GradeResult {
+	userAnswer = strings.TrimSpace(userAnswer)
+	correctAnswer := strings.TrimSpace(question.Answer)
+	if userAnswer == "" {
+		return models.GradeResult{}
+	}
+
+	switch question.Type {
+	case "single", "judge":
+		return fullCredit(strings.EqualFold(userAnswer, correctAnswer))
+	case "multiple":
+		return fullCredit(compareMultipleChoiceAnswer(userAnswer, correctAnswer))
+	case "fill":
+		return gradeFillAnswer(question, userAnswer)
+	case "short":
+		return gradeShortAnswer(question, userAnswer)
+	case "ordering":
+		return gradeOrderingAnswer(userAnswer, correctAnswer)
+	case "matching":
+		return gradeMatchingAnswer(userAnswer, correctAnswer)
+	}
+	return models.GradeResult{}
+}
+
+// fullCredit 只有对错的判分结果
+func fullCredit(correct bool) models.GradeResult {
+	if correct {
+		return models.GradeResult{IsCorrect: true, Score: 1}
+	}
+	return models.GradeResult{}
+}
+
+// compareMultipleChoiceAnswer 比较多选题答案
+// 兼容 "A,B" 与 "AB" 两种写法，忽略顺序和大小写
+func compareMultipleChoiceAnswer(userAnswer, correctAnswer string) bool {
+	userSet := answerLetterSet(userAnswer)
+	correctSet := answerLetterSet(correctAnswer)
+
+	// 检查选项数量是否相同
+	if len(userSet) == 0 || len(userSet) != len(correctSet) {
+		return false
+	}
+
+	// 检查每个选项是否都匹配
+	for letter := range correctSet {
+		if !userSet[letter] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// answerLetterSet 提取答案中的选项字母集合
+func answerLetterSet(answer string) map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range strings.ToUpper(answer) {
+		if r >= 'A' && r <= 'Z' {
+			set[r] = true
+		}
+	}
+	return set
+}
+
+// answerLetterSequence 按出现顺序提取答案中的选项字母，重复的字母只保留第一次
+func answerLetterSequence(answer string) []rune {
+	seen := make(map[rune]bool)
+	var letters []rune
+	for _, r := range strings.ToUpper(answer) {
+		if r >= 'A' && r <= 'Z' && !seen[r] {
+			seen[r] = true
+			letters = append(letters, r)
+		}
+	}
+	return letters
+}
+
+// gradeOrderingAnswer 排序题判分，答案为按正确顺序排列的选项字母（如"CABD"）
+// 得分为用户顺序与正确顺序的最长公共子序列长度占总项数的比例，顺序完全一致才判为正确
+func gradeOrderingAnswer(userAnswer, correctAnswer string) models.GradeResult {
+	correct := answerLetterSequence(correctAnswer)
+	if len(correct) == 0 {
+		return models.GradeResult{}
+	}
+	user := answerLetterSequence(userAnswer)
+
+	// lcs[j] 为用户顺序前缀与正确顺序前j项的最长公共子序列长度
+	lcs := make([]int, len(correct)+1)
+	for _, u := range user {
+		prev := 0
+		for j, c := range correct {
+			current := lcs[j+1]
+			if u == c {
+				lcs[j+1] = prev + 1
+			} else if lcs[j] > lcs[j+1] {
+				lcs[j+1] = lcs[j]
+			}
+			prev = current
+		}
+	}
+
+	length := lcs[len(correct)]
+	return models.GradeResult{
+		IsCorrect: length == len(correct) && len(user) == len(correct),
+		Score:     roundScore(float64(length) / float64(len(correct))),
+	}
+}
+
+// ParseMatchingAnswer 解析匹配题答案，返回左侧编号到右侧选项字母的映射
+// 答案写作"1B,2A,3C"，编号与字母之间可加"-"、":"等分隔符；同一编号出现多次时以第一次为准
+func ParseMatchingAnswer(answer string) map[string]string {
+	pairs := make(map[string]string)
+	for _, m := range matchingPairPattern.FindAllStringSubmatch(strings.ToUpper(answer), -1) {
+		prompt := strings.TrimLeft(m[1], "0")
+		if _, ok := pairs[prompt]; !ok && prompt != "" {
+			pairs[prompt] = m[2]
+		}
+	}
+	return pairs
+}
+
+// matchingPairPattern 匹配题答案中的"编号-字母"配对
+var matchingPairPattern = regexp.MustCompile(`(\d+)\s*[-:：=>→]*\s*([A-Z])`)
+
+// gradeMatchingAnswer 匹配题判分，得分为配对正确的左侧项比例
+func gradeMatchingAnswer(userAnswer, correctAnswer string) models.GradeResult {
+	correct := ParseMatchingAnswer(correctAnswer)
+	if len(correct) == 0 {
+		return models.GradeResult{}
+	}
+	user := ParseMatchingAnswer(userAnswer)
+
+	matched := 0
+	for prompt, letter := range correct {
+		if user[prompt] == letter {
+			matched++
+		}
+	}
+	return models.GradeResult{
+		IsCorrect: matched == len(correct),
+		Score:     roundScore(float64(matched) / float64(len(correct))),
+	}
+}
+
+// gradeFillAnswer 填空题判分，用户答案各空以";"或换行分隔，得分为答对的空数比例
+func gradeFillAnswer(question *models.Question, userAnswer string) models.GradeResult {
+	blanks := ParseFillAnswers(question.Answer)
+	if len(blanks) == 0 {
+		return models.GradeResult{}
+	}
+	rules := normalizeRules(question.Grading)
+	answers := splitFillBlanks(userAnswer)
+
+	matches := func(accepted []string, answer string) bool {
+		answer = normalizeAnswerText(answer, rules)
+		for _, candidate := range accepted {
+			if normalizeAnswerText(candidate, rules) == answer {
+				return true
+			}
+		}
+		return false
+	}
+
+	correct := 0
+	if question.Grading != nil && question.Grading.Unordered {
+		used := make([]bool, len(blanks))
+		for _, answer := range answers {
+			for i, accepted := range blanks {
+				if !used[i] && matches(accepted, answer) {
+					used[i] = true
+					correct++
+					break
+				}
+			}
+		}
+	} else {
+		for i := 0; i < len(blanks) && i < len(answers); i++ {
+			if matches(blanks[i], answers[i]) {
+				correct++
+			}
+		}
+	}
+
+	return models.GradeResult{
+		IsCorrect: correct == len(blanks),
+		Score:     roundScore(float64(correct) / float64(len(blanks))),
+	}
+}
+
+// gradeShortAnswer 简答题按关键词评分
+// 得分比例达到及格线时判为正确，未命中任何关键词时判为错误，其余情况及未设置关键词时等待人工复核
+func gradeShortAnswer(question *models.Question, userAnswer string) models.GradeResult {
+	var keywords []string
+	passScore := DefaultPassScore
+	if question.Grading != nil {
+		keywords = question.Grading.Keywords
+		if question.Grading.PassScore > 0 {
+			passScore = question.Grading.PassScore
+		}
+	}
+	if len(keywords) == 0 {
+		return models.GradeResult{ReviewStatus: ReviewStatusPending}
+	}
+
+	rules := normalizeRules(nil)
+	text := normalizeAnswerText(userAnswer, rules)
+	var matched []string
+	for _, keyword := range keywords {
+		synonyms := splitAlternatives(keyword)
+		for _, synonym := range synonyms {
+			if s := normalizeAnswerText(synonym, rules); s != "" && strings.Contains(text, s) {
+				matched = append(matched, synonyms[0])
+				break
+			}
+		}
+	}
+
+	result := models.GradeResult{
+		Score:           roundScore(float64(len(matched)) / float64(len(keywords))),
+		MatchedKeywords: matched,
+	}
+	switch {
+	case result.Score >= passScore:
+		result.IsCorrect = true
+	case len(matched) > 0:
+		result.ReviewStatus = ReviewStatusPending
+	}
+	return result
+}
+
+// ParseFillAnswers 解析填空题答案，返回每个空的可接受答案
+// 各空以";"分隔，同一空的多个可接受答案以"|"分隔（全角半角均可）
+func ParseFillAnswers(answer string) [][]string {
+	var blanks [][]string
+	for _, blank := range splitFillBlanks(answer) {
+		if accepted := splitAlternatives(blank); len(accepted) > 0 {
+			blanks = append(blanks, accepted)
+		}
+	}
+	return blanks
+}
+
+// splitFillBlanks 按";"或换行切分各空
+func splitFillBlanks(s string) []string {
+	return splitNonEmpty(s, func(r rune) bool {
+		return r == ';' || r == '；' || r == '\n'
+	})
+}
+
+// splitAlternatives 按"|"切分可接受答案或同义词
+func splitAlternatives(s string) []string {
+	return splitNonEmpty(s, func(r rune) bool {
+		return r == '|' || r == '｜'
+	})
+}
+
+// splitNonEmpty 切分并去除空白项
+func splitNonEmpty(s string, sep func(rune) bool) []string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// normalizeRules 获取启用的归一化规则，未配置时全部启用
+func normalizeRules(rule *models.GradingRule) map[string]bool {
+	rules := make(map[string]bool, len(normalizeRuleNames))
+	if rule == nil || len(rule.Normalize) == 0 {
+		for _, name := range normalizeRuleNames {
+			rules[name] = true
+		}
+		return rules
+	}
+	for _, name := range rule.Normalize {
+		rules[name] = true
+	}
+	return rules
+}
+
+// normalizeAnswerText 按规则归一化答案文本
+func normalizeAnswerText(s string, rules map[string]bool) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		if rules["width"] {
+			r = foldWidth(r)
+		}
+		if rules["space"] && unicode.IsSpace(r) {
+			continue
+		}
+		if rules["punct"] && (unicode.IsPunct(r) || unicode.IsSymbol(r)) {
+			continue
+		}
+		if rules["case"] {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// foldWidth 全角字符转为半角
+func foldWidth(r rune) rune {
+	switch {
+	case r == '　':
+		return ' '
+	case r >= '！' && r <= '～':
+		return r - 0xfee0
+	}
+	return r
+}
+
+// roundScore 得分保留3位小数
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// prepareGradingRule 校验并整理判分规则，选择题和判断题不使用判分规则
+func prepareGradingRule(qType, answer string, rule *models.GradingRule) (*models.GradingRule, error) {
+	if qType != "fill" && qType != "short" {
+		return nil, nil
+	}
+	if qType == "fill" && len(ParseFillAnswers(answer)) == 0 {
+		return nil, fmt.Errorf("%w: fill-in-the-blank answer must list accepted answers for each blank", ErrInvalidGrading)
+	}
+	if rule == nil {
+		return nil, nil
+	}
+
+	prepared := &models.GradingRule{PassScore: rule.PassScore}
+	if rule.PassScore < 0 || rule.PassScore > 1 {
+		return nil, fmt.Errorf("%w: pass_score must be between 0 and 1", ErrInvalidGrading)
+	}
+	if qType == "fill" {
+		prepared.Unordered = rule.Unordered
+		for _, name := range rule.Normalize {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "none" {
+				prepared.Normalize = []string{"none"}
+				break
+			}
+			if !containsString(normalizeRuleNames, name) {
+				return nil, fmt.Errorf("%w: unknown normalize rule %q", ErrInvalidGrading, name)
+			}
+			if !containsString(prepared.Normalize, name) {
+				prepared.Normalize = append(prepared.Normalize, name)
+			}
+		}
+		prepared.PassScore = 0
+	} else {
+		for _, keyword := range rule.Keywords {
+			if synonyms := splitAlternatives(keyword); len(synonyms) > 0 {
+				prepared.Keywords = append(prepared.Keywords, strings.Join(synonyms, "|"))
+			}
+		}
+	}
+
+	if len(prepared.Normalize) == 0 && !prepared.Unordered && len(prepared.Keywords) == 0 && prepared.PassScore == 0 {
+		return nil, nil
+	}
+	return prepared, nil
+}
+
+// validateStructuredAnswer 校验排序题和匹配题的选项与答案结构
+// 排序题答案须恰好包含每个选项字母一次；匹配题选项中数字编号为左侧项、字母为右侧候选项，
+// 每个左侧项须配对一个已有的右侧候选项，右侧可以有多余的干扰项
+func validateStructuredAnswer(qType string, options map[string]string, answer string) error {
+	switch qType {
+	case "ordering":
+		letters := answerLetterSequence(answer)
+		all := strings.Map(keepLetters, strings.ToUpper(answer))
+		if len(options) < 2 || len(letters) != len(options) || len(all) != len(letters) {
+			return fmt.Errorf("%w: ordering answer must list every option letter exactly once", ErrInvalidGrading)
+		}
+		for _, letter := range letters {
+			if _, ok := options[string(letter)]; !ok {
+				return fmt.Errorf("%w: ordering answer %q does not match the options", ErrInvalidGrading, answer)
+			}
+		}
+	case "matching":
+		var prompts []string
+		for key := range options {
+			if _, err := strconv.Atoi(key); err == nil {
+				prompts = append(prompts, key)
+			} else if !isOptionLetter(key) {
+				return fmt.Errorf("%w: matching option %q must be a number (left item) or a letter (right item)", ErrInvalidGrading, key)
+			}
+		}
+		sort.Strings(prompts)
+		pairs := ParseMatchingAnswer(answer)
+		if len(prompts) == 0 || len(pairs) != len(prompts) {
+			return fmt.Errorf("%w: matching answer must pair every numbered item with a lettered option", ErrInvalidGrading)
+		}
+		for _, prompt := range prompts {
+			letter, ok := pairs[strings.TrimLeft(prompt, "0")]
+			if !ok {
+				return fmt.Errorf("%w: matching answer has no pair for item %s", ErrInvalidGrading, prompt)
+			}
+			if _, ok := options[letter]; !ok {
+				return fmt.Errorf("%w: matching answer pairs item %s with unknown option %s", ErrInvalidGrading, prompt, letter)
+			}
+		}
+	}
+	return nil
+}
+
+// keepLetters 只保留大写字母，用于检查排序题答案中的重复字母
+func keepLetters(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r
+	}
+	return -1
+}
+
+// isOptionLetter 判断选项键是否为单个大写字母
+func isOptionLetter(key string) bool {
+	return len(key) == 1 && key[0] >= 'A' && key[0] <= 'Z'
+}
+
+// gradingEqual 判断两个判分规则是否相同
+func gradingEqual(a, b *models.GradingRule) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
+
+// containsString 切片中是否包含字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+)
+
+// 题库文件大小上限
+const (
+	MaxImportFileSize = 50 << 20 // 上传的题库文件
+	maxZipEntrySize   = 20 << 20 // 压缩包中单个题目、清单或题库文件解压后的大小
+)
+
+// ErrImportFileTooLarge 上传的题库文件过大
+var ErrImportFileTooLarge = fmt.Errorf("question file cannot exceed %d MB", MaxImportFileSize>>20)
+
+// ParseQuestions 按指定格式解析题库文件，得到待导入的题目数据
+func ParseQuestions(r io.Reader, format string) ([]QuestionData, error) {
+	switch format {
+	case FormatJSON:
+		var questionFile QuestionFile
+		if err := json.NewDecoder(r).Decode(&questionFile); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		return questionFile.Questions, nil
+	case FormatCSV:
+		return parseQuestionsCSV(r)
+	case FormatQTI:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return parseQTI(data)
+	case FormatGIFT:
+		return parseGIFT(r)
+	case FormatAiken:
+		return parseAiken(r)
+	case FormatBundle:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return parseBundle(data)
+	}
+	return nil, fmt.Errorf("unsupported import format: %s", format)
+}
+
+// FormatFromFilename 根据文件扩展名推断导入格式
+func FormatFromFilename(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".gift.txt"):
+		return FormatGIFT
+	case strings.HasSuffix(lower, ".aiken.txt"):
+		return FormatAiken
+	case strings.HasSuffix(lower, ".bundle.zip"):
+		return FormatBundle
+	}
+
+	switch path.Ext(lower) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	case ".zip", ".xml":
+		return FormatQTI
+	case ".gift":
+		return FormatGIFT
+	case ".aiken":
+		return FormatAiken
+	}
+	return ""
+}
+
+// parseQuestionsCSV 解析由exportCSV导出的CSV文件，按表头识别列
+func parseQuestionsCSV(r io.Reader) ([]QuestionData, error) {
+	// 跳过Excel写入的BOM
+	br := bufio.NewReader(r)
+	if bom, _, err := br.ReadRune(); err == nil && bom != '\ufeff' {
+		br.UnreadRune()
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"type", "question", "answer"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing column %q", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var questions []QuestionData
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		qd := QuestionData{
+			Type:        field(record, "type"),
+			Question:    field(record, "question"),
+			Answer:      field(record, "answer"),
+			Category:    field(record, "category"),
+			Explanation: field(record, "explanation"),
+			Tags:        ParseTags(field(record, "tags")),
+		}
+		if id := field(record, "id"); id != "" {
+			if qd.ID, err = strconv.Atoi(id); err != nil {
+				return nil, fmt.Errorf("line %d: invalid id %q", line, id)
+			}
+		}
+		if difficulty := field(record, "difficulty"); difficulty != "" {
+			if qd.Difficulty, err = strconv.Atoi(difficulty); err != nil {
+				return nil, fmt.Errorf("line %d: invalid difficulty %q", line, difficulty)
+			}
+		}
+		if grading := field(record, "grading"); grading != "" {
+			qd.Grading = &models.GradingRule{}
+			if err := json.Unmarshal([]byte(grading), qd.Grading); err != nil {
+				return nil, fmt.Errorf("line %d: invalid grading %q", line, grading)
+			}
+		}
+		for _, key := range csvOptionKeys {
+			if text := field(record, key); text != "" {
+				if qd.Options == nil {
+					qd.Options = make(map[string]string)
+				}
+				qd.Options[key] = text
+			}
+		}
+
+		questions = append(questions, qd)
+	}
+
+	return questions, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// 贝叶斯知识追踪（BKT）参数
+const (
+	bktInitial = 0.3 // 首次作答前已掌握的先验概率
+	bktLearn   = 0.1 // 每次作答后从未掌握转为掌握的概率
+	bktSlip    = 0.1 // 已掌握但答错的概率
+	// MasteredThreshold 掌握概率达到该值视为已掌握
+	MasteredThreshold = 0.95
+	// minWeakAreaAttempts 作答次数达到该值的分类才参与薄弱分类排序
+	minWeakAreaAttempts = 3
+)
+
+// bktGuess 各题型未掌握时猜对的概率，未列出的题型按0.05计
+var bktGuess = map[string]float64{
+	"single":   0.25,
+	"multiple": 0.1,
+	"judge":    0.5,
+}
+
+// guessProbability 题型的猜对概率
+func guessProbability(questionType string) float64 {
+	if guess, ok := bktGuess[questionType]; ok {
+		return guess
+	}
+	return 0.05
+}
+
+// applyBKT 按一次作答更新掌握概率，score为得分比例0-1，部分得分按比例混合答对和答错的后验
+func applyBKT(pKnown, score float64, questionType string) float64 {
+	guess := guessProbability(questionType)
+
+	pCorrect := pKnown*(1-bktSlip) + (1-pKnown)*guess
+	pWrong := pKnown*bktSlip + (1-pKnown)*(1-guess)
+	posterior := score*(pKnown*(1-bktSlip)/pCorrect) + (1-score)*(pKnown*bktSlip/pWrong)
+
+	return posterior + (1-posterior)*bktLearn
+}
+
+// applyMasteryAnswer 按一次作答更新分类掌握程度
+func applyMasteryAnswer(mastery *models.CategoryMastery, answer *models.UserAnswer, questionType string, now time.Time) {
+	score := answer.Score
+	if answer.IsCorrect && score == 0 {
+		score = 1
+	}
+	mastery.PKnown = applyBKT(mastery.PKnown, score, questionType)
+	mastery.Attempts++
+	if answer.IsCorrect {
+		mastery.Correct++
+	}
+	mastery.LastAnsweredAt = now
+}
+
+// updateMastery 按答题记录更新分类掌握程度，需在保存答题记录的事务中调用；等待人工复核的记录复核后再计入
+func updateMastery(tx *gorm.DB, answer *models.UserAnswer) error {
+	if answer.ReviewStatus == ReviewStatusPending {
+		return nil
+	}
+
+	var mastery models.CategoryMastery
+	err := tx.Where("user_id = ? AND category = ?", answer.UserID, answer.Category).First(&mastery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		mastery = models.CategoryMastery{UserID: answer.UserID, Category: answer.Category, PKnown: bktInitial}
+	} else if err != nil {
+		return err
+	}
+
+	questionType := ""
+	if question, err := Cache.GetQuestion(answer.QuestionID); err == nil {
+		questionType = question.Type
+	}
+	now := answer.AnsweredAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	applyMasteryAnswer(&mastery, answer, questionType, now)
+	return tx.Save(&mastery).Error
+}
+
+// GetCategoryMastery 获取用户各分类的掌握程度，键为分类
+func GetCategoryMastery(userID uint, category string) (map[string]models.CategoryMastery, error) {
+	var rows []models.CategoryMastery
+	if err := DB.Scopes(CategoryScope("category", category)).
+		Where("user_id = ?", userID).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]models.CategoryMastery, len(rows))
+	for _, row := range rows {
+		result[row.Category] = row
+	}
+	return result, nil
+}
+
+// GetWeakAreas 按掌握程度从低到高获取薄弱分类，只包含作答次数足够且尚未掌握的分类
+func GetWeakAreas(userID uint, limit int) ([]models.WeakArea, error) {
+	var rows []models.CategoryMastery
+	if err := DB.Where("user_id = ? AND attempts >= ? AND p_known < ?", userID, minWeakAreaAttempts, MasteredThreshold).
+		Order("p_known, last_answered_at DESC").
+		Limit(limit).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []models.WeakArea{}, nil
+	}
+
+	coverage, err := GetCoverage(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	unseen := make(map[string]int, len(coverage.Categories))
+	for _, stats := range coverage.Categories {
+		unseen[stats.Name] = stats.Unseen
+	}
+
+	areas := make([]models.WeakArea, len(rows))
+	for i, row := range rows {
+		areas[i] = models.WeakArea{
+			Category:   row.Category,
+			Mastery:    row.PKnown * 100,
+			Attempts:   row.Attempts,
+			Accuracy:   float64(row.Correct) / float64(row.Attempts) * 100,
+			Unseen:     unseen[row.Category],
+			LastAnswer: row.LastAnsweredAt,
+		}
+	}
+	return areas, nil
+}
+
+// backfillCategoryMastery 掌握程度为空时，按时间顺序回放已有答题记录估计各分类的掌握程度
+func backfillCategoryMastery() error {
+	var masteryCount, answerCount int64
+	if err := DB.Model(&models.CategoryMastery{}).Count(&masteryCount).Error; err != nil {
+		return err
+	}
+	if masteryCount > 0 {
+		return nil
+	}
+	if err := DB.Model(&models.UserAnswer{}).Where("review_status <> ?", ReviewStatusPending).Count(&answerCount).Error; err != nil {
+		return err
+	}
+	if answerCount == 0 {
+		return nil
+	}
+
+	var types []struct {
+		ID   uint
+		Type string
+	}
+	if err := DB.Model(&models.Question{}).Select("id, type").Scan(&types).Error; err != nil {
+		return err
+	}
+	typeOf := make(map[uint]string, len(types))
+	for _, t := range types {
+		typeOf[t.ID] = t.Type
+	}
+
+	type masteryKey struct {
+		userID   uint
+		category string
+	}
+	masteries := make(map[masteryKey]*models.CategoryMastery)
+	err := replayGradedAnswers(func(answer *models.UserAnswer) {
+		key := masteryKey{answer.UserID, answer.Category}
+		mastery, ok := masteries[key]
+		if !ok {
+			mastery = &models.CategoryMastery{UserID: answer.UserID, Category: answer.Category, PKnown: bktInitial}
+			masteries[key] = mastery
+		}
+		applyMasteryAnswer(mastery, answer, typeOf[answer.QuestionID], answer.AnsweredAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*models.CategoryMastery, 0, len(masteries))
+	for _, mastery := range masteries {
+		batch = append(batch, mastery)
+	}
+	if err := DB.CreateInBatches(batch, 500).Error; err != nil {
+		return err
+	}
+	log.Printf("Estimated mastery for %d categories from %d answers", len(batch), answerCount)
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// CreateNotification 创建站内通知，tx可为事务或DB
+func CreateNotification(tx *gorm.DB, notification *models.Notification) error {
+	return tx.Create(notification).Error
+}
+
+// GetNotifications 获取用户通知（新通知在前）及未读数量
+func GetNotifications(userID uint, unreadOnly bool, limit int) ([]models.Notification, int64, error) {
+	query := DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var unread int64
+	if err := DB.Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Count(&unread).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkNotificationRead 将通知标记为已读，通知不属于该用户时返回gorm.ErrRecordNotFound
+func MarkNotificationRead(userID, notificationID uint) error {
+	result := DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("is_read", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead 将用户所有通知标记为已读，返回更新的数量
+func MarkAllNotificationsRead(userID uint) (int64, error) {
+	result := DB.Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Update("is_read", true)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 学习计划中的题目类别
+const (
+	PlanKindReview = "review" // 到期复习
+	PlanKindWeak   = "weak"   // 薄弱分类强化
+	PlanKindNew    = "new"    // 未做过的新题
+)
+
+// 学习计划参数
+const (
+	planDateLayout        = "2006-01-02"
+	defaultDailyQuestions = 30
+	maxDailyQuestions     = 300
+	maxDailyMinutes       = 600
+	planReviewShare       = 0.4 // 到期复习最多占预算的比例，其余题量留给薄弱分类和新题
+	planNewShare          = 0.3 // 未设置考试日期时新题占预算的比例
+	planWeakShare         = 0.3 // 有薄弱分类时至少留给强化的比例，避免按考试日期均摊的新题占满预算
+	planFinalWeekNewShare = 0.2 // 考前一周新题最多占预算的比例，以复习和强化为主
+	planWeakAreas         = 5   // 参与强化的薄弱分类数
+)
+
+// ErrInvalidStudySettings 每日学习设置超出范围
+var ErrInvalidStudySettings = fmt.Errorf("daily_questions must be between 0 and %d, daily_minutes between 0 and %d, exam_date in YYYY-MM-DD format",
+	maxDailyQuestions, maxDailyMinutes)
+
+// GetStudySettings 获取每日学习设置，未设置过时返回默认值
+func GetStudySettings(userID uint) (*models.StudySettings, error) {
+	var settings models.StudySettings
+	err := DB.Where("user_id = ?", userID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.StudySettings{UserID: userID, DailyQuestions: defaultDailyQuestions}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateStudySettings 更新每日学习设置，已生成的当天计划不受影响，可重新生成
+func UpdateStudySettings(userID uint, req *models.StudySettingsRequest) (*models.StudySettings, error) {
+	settings, err := GetStudySettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.DailyQuestions != nil {
+		if *req.DailyQuestions < 0 || *req.DailyQuestions > maxDailyQuestions {
+			return nil, ErrInvalidStudySettings
+		}
+		settings.DailyQuestions = *req.DailyQuestions
+	}
+	if req.DailyMinutes != nil {
+		if *req.DailyMinutes < 0 || *req.DailyMinutes > maxDailyMinutes {
+			return nil, ErrInvalidStudySettings
+		}
+		settings.DailyMinutes = *req.DailyMinutes
+	}
+	if req.ExamDate != nil {
+		if *req.ExamDate == "" {
+			settings.ExamDate = nil
+		} else {
+			date, err := time.ParseInLocation(planDateLayout, *req.ExamDate, time.Local)
+			if err != nil {
+				return nil, ErrInvalidStudySettings
+			}
+			settings.ExamDate = &date
+		}
+	}
+
+	if err := DB.Save(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// planBudget 按每日题量和每日时间（每题1分钟）确定题量预算，两者都设置时取较小值
+func planBudget(settings *models.StudySettings) int {
+	budget := settings.DailyQuestions
+	if settings.DailyMinutes > 0 && (budget == 0 || settings.DailyMinutes < budget) {
+		budget = settings.DailyMinutes
+	}
+	if budget == 0 {
+		budget = defaultDailyQuestions
+	}
+	return budget
+}
+
+// daysUntilExam 今天到考试日期的天数，未设置或已过考试日期时返回nil
+func daysUntilExam(settings *models.StudySettings, today time.Time) *int {
+	if settings.ExamDate == nil {
+		return nil
+	}
+	exam := time.Date(settings.ExamDate.Year(), settings.ExamDate.Month(), settings.ExamDate.Day(), 0, 0, 0, 0, today.Location())
+	days := int(math.Round(exam.Sub(today).Hours() / 24))
+	if days < 0 {
+		return nil
+	}
+	return &days
+}
+
+// GetTodayPlan 获取当天的学习计划，当天首次获取时生成
+// rebuild为true时保留已完成的题目，按当前设置和学习情况重新安排其余题目
+func GetTodayPlan(userID uint, rebuild bool) (*models.StudyPlan, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	date := today.Format(planDateLayout)
+
+	var plan models.StudyPlan
+	err := DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Where("user_id = ? AND date = ?", userID, date).
+		First(&plan).Error
+	exists := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if exists && !rebuild {
+		return &plan, nil
+	}
+
+	settings, err := GetStudySettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []models.StudyPlanItem
+	for _, item := range plan.Items {
+		if item.Done {
+			done = append(done, item)
+		}
+	}
+	exclude := make([]uint, len(done))
+	for i, item := range done {
+		exclude[i] = item.QuestionID
+	}
+
+	plan.UserID = userID
+	plan.Date = date
+	plan.Budget = planBudget(settings)
+	plan.DaysUntilExam = daysUntilExam(settings, today)
+	items, err := buildPlanItems(userID, plan.Budget-len(done), plan.DaysUntilExam, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if exists {
+			if err := tx.Where("plan_id = ? AND done = ?", plan.ID, false).Delete(&models.StudyPlanItem{}).Error; err != nil {
+				return err
+			}
+		}
+		plan.Items = nil
+		if err := tx.Save(&plan).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].PlanID = plan.ID
+			items[i].SortOrder = len(done) + i + 1
+		}
+		if len(items) > 0 {
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// 并发请求已生成当天计划时返回已有计划
+		if !exists {
+			var existing models.StudyPlan
+			if DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+				Where("user_id = ? AND date = ?", userID, date).
+				First(&existing).Error == nil {
+				return &existing, nil
+			}
+		}
+		return nil, err
+	}
+
+	plan.Items = append(done, items...)
+	return &plan, nil
+}
+
+// buildPlanItems 按预算安排题目：先安排到期复习，再按考试日期和题库覆盖情况安排新题，其余用于薄弱分类强化
+// 某类题目不足时由其他类补足
+func buildPlanItems(userID uint, budget int, examDays *int, exclude []uint) ([]models.StudyPlanItem, error) {
+	if budget <= 0 {
+		return nil, nil
+	}
+
+	coverage, err := GetCoverage(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	summary, err := GetReviewSummary(userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reviewQuota := minInt(int(summary.Due), int(math.Ceil(float64(budget)*planReviewShare)))
+	newTarget := int(math.Ceil(float64(budget) * planNewShare))
+	if examDays != nil {
+		// 按剩余天数均摊未做过的题目，考前一周以复习和强化为主
+		newTarget = int(math.Ceil(float64(coverage.Unseen) / float64(maxInt(*examDays, 1))))
+		if *examDays <= 7 {
+			newTarget = minInt(newTarget, int(math.Ceil(float64(budget)*planFinalWeekNewShare)))
+		}
+	}
+	areas, err := GetWeakAreas(userID, planWeakAreas)
+	if err != nil {
+		return nil, err
+	}
+	weakReserve := 0
+	if len(areas) > 0 {
+		weakReserve = int(math.Ceil(float64(budget) * planWeakShare))
+	}
+	newQuota := maxInt(minInt(newTarget, budget-reviewQuota-weakReserve), 0)
+
+	var items []models.StudyPlanItem
+	selected := append([]uint(nil), exclude...)
+	add := func(kind string, questions []planCandidate) {
+		for _, q := range questions {
+			items = append(items, models.StudyPlanItem{QuestionID: q.ID, Kind: kind, Category: q.Category})
+			selected = append(selected, q.ID)
+		}
+	}
+
+	reviews, err := planReviewQuestions(userID, reviewQuota, selected)
+	if err != nil {
+		return nil, err
+	}
+	add(PlanKindReview, reviews)
+
+	weak, err := planWeakQuestions(userID, areas, budget-len(items)-newQuota, selected)
+	if err != nil {
+		return nil, err
+	}
+	add(PlanKindWeak, weak)
+
+	// 薄弱分类题目不足时由新题补足
+	fresh, err := planNewQuestions(userID, budget-len(items), selected)
+	if err != nil {
+		return nil, err
+	}
+	add(PlanKindNew, fresh)
+
+	// 新题也不足时安排更多到期复习
+	if len(items) < budget {
+		more, err := planReviewQuestions(userID, budget-len(items), selected)
+		if err != nil {
+			return nil, err
+		}
+		add(PlanKindReview, more)
+	}
+	return items, nil
+}
+
+// planCandidate 计划候选题目
+type planCandidate struct {
+	ID       uint
+	Category string
+}
+
+// planReviewQuestions 最早到期的复习题目
+func planReviewQuestions(userID uint, limit int, exclude []uint) ([]planCandidate, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	var candidates []planCandidate
+	query := DB.Model(&models.ReviewCard{}).
+		Select("question_id AS id, category").
+		Where("user_id = ? AND due_at <= ?", userID, time.Now())
+	if len(exclude) > 0 {
+		query = query.Where("question_id NOT IN ?", exclude)
+	}
+	err := query.Order("due_at, id").Limit(limit).Scan(&candidates).Error
+	return candidates, err
+}
+
+// planWeakQuestions 按掌握程度从低到高的薄弱分类分配题量，越薄弱分配越多
+// 分类内优先错题本中的题目，其次未做过的，最后是做过的
+func planWeakQuestions(userID uint, areas []models.WeakArea, quota int, exclude []uint) ([]planCandidate, error) {
+	if quota <= 0 || len(areas) == 0 {
+		return nil, nil
+	}
+
+	weights := make([]float64, len(areas))
+	total := 0.0
+	for i, area := range areas {
+		weights[i] = math.Max(1-area.Mastery/100, 0.05)
+		total += weights[i]
+	}
+
+	var result []planCandidate
+	for i, area := range areas {
+		remaining := quota - len(result)
+		if remaining <= 0 {
+			break
+		}
+		count := int(math.Round(float64(quota) * weights[i] / total))
+		if i == 0 {
+			count = int(math.Ceil(float64(quota) * weights[i] / total))
+		}
+		if i == len(areas)-1 || count > remaining {
+			count = remaining
+		}
+		if count == 0 {
+			continue
+		}
+
+		query := DB.Model(&models.Question{}).Select("id, category").Where("category = ?", area.Category)
+		if len(exclude) > 0 {
+			query = query.Where("id NOT IN ?", exclude)
+		}
+		if len(result) > 0 {
+			query = query.Where("id NOT IN ?", candidateIDs(result))
+		}
+		wrong := DB.Model(&models.WrongBookEntry{}).Select("question_id").
+			Where("user_id = ? AND status = ?", userID, WrongStatusActive)
+		var candidates []planCandidate
+		if err := query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "CASE WHEN id IN (?) THEN 0 WHEN id IN (?) THEN 2 ELSE 1 END, RANDOM()",
+			Vars:               []interface{}{wrong, answeredQuestions(userID)},
+			WithoutParentheses: true,
+		}}).Limit(count).Scan(&candidates).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, candidates...)
+	}
+	return result, nil
+}
+
+// planNewQuestions 随机抽取未做过的题目
+func planNewQuestions(userID uint, limit int, exclude []uint) ([]planCandidate, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	var candidates []planCandidate
+	query := DB.Model(&models.Question{}).
+		Select("id, category").
+		Where("id NOT IN (?)", answeredQuestions(userID))
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	err := query.Order("RANDOM()").Limit(limit).Scan(&candidates).Error
+	return candidates, err
+}
+
+// candidateIDs 候选题目的ID列表
+func candidateIDs(candidates []planCandidate) []uint {
+	ids := make([]uint, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// markPlanItemDone 将作答当天学习计划中的该题标记为完成，需在保存答题记录的事务中调用
+// 交卷时未作答的题目不计为完成
+func markPlanItemDone(tx *gorm.DB, answer *models.UserAnswer) error {
+	if answer.UserAnswer == "" {
+		return nil
+	}
+	answeredAt := answer.AnsweredAt
+	if answeredAt.IsZero() {
+		answeredAt = time.Now()
+	}
+	plans := tx.Model(&models.StudyPlan{}).Select("id").
+		Where("user_id = ? AND date = ?", answer.UserID, answeredAt.Format(planDateLayout))
+	return tx.Model(&models.StudyPlanItem{}).
+		Where("plan_id IN (?) AND question_id = ? AND done = ?", plans, answer.QuestionID, false).
+		Updates(map[string]interface{}{
+			"done":         true,
+			"completed_at": answeredAt,
+		}).Error
+}
+
+// PlanSections 按题目类别统计学习计划的完成情况
+func PlanSections(plan *models.StudyPlan) []models.PlanSection {
+	sections := []models.PlanSection{{Kind: PlanKindReview}, {Kind: PlanKindWeak}, {Kind: PlanKindNew}}
+	for _, item := range plan.Items {
+		for i := range sections {
+			if sections[i].Kind != item.Kind {
+				continue
+			}
+			sections[i].Total++
+			if item.Done {
+				sections[i].Completed++
+			}
+		}
+	}
+	return sections
+}
+
+// GetPlanHistory 获取最近days天的学习计划完成情况，最近的在前；streak为截至今天（今天未完成时截至昨天）连续完成计划的天数
+func GetPlanHistory(userID uint, days int) ([]models.PlanDay, int, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -days+1).Format(planDateLayout)
+
+	var history []models.PlanDay
+	if err := DB.Table("study_plans p").
+		Select("p.date, count(i.id) AS total, coalesce(sum(case when i.done then 1 else 0 end), 0) AS completed").
+		Joins("LEFT JOIN study_plan_items i ON i.plan_id = p.id").
+		Where("p.user_id = ? AND p.date >= ?", userID, since).
+		Group("p.id, p.date").
+		Order("p.date DESC").
+		Scan(&history).Error; err != nil {
+		return nil, 0, err
+	}
+
+	completedDays := make(map[string]bool, len(history))
+	for i := range history {
+		if history[i].Total > 0 {
+			history[i].Completion = float64(history[i].Completed) / float64(history[i].Total) * 100
+		}
+		completedDays[history[i].Date] = history[i].Total > 0 && history[i].Completed == history[i].Total
+	}
+
+	streak := 0
+	day := today
+	if !completedDays[day.Format(planDateLayout)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for completedDays[day.Format(planDateLayout)] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return history, streak, nil
+}
+
+// minInt 返回较小值
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt 返回较大值
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
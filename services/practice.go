@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuestionNotInCategory 题目不属于指定分类
+var ErrQuestionNotInCategory = errors.New("question does not belong to the category")
+
+// ErrEmptyCategory 分类下没有题目
+var ErrEmptyCategory = errors.New("no questions in category")
+
+// GetSequentialQuestions 按题目ID顺序获取分类中当前位置之后的题目，restart为true时从头开始并清除已保存的位置
+// 分类树的父节点包含所有子分类；已练完整个分类时返回空列表且进度的Finished为true
+func GetSequentialQuestions(userID uint, category string, limit int, restart bool) ([]models.Question, *models.SequentialProgress, error) {
+	if restart {
+		if err := ResetSequentialPosition(userID, category); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	progress, err := sequentialProgress(userID, category)
+	if err != nil {
+		return nil, nil, err
+	}
+	if progress.Total == 0 {
+		return nil, nil, ErrEmptyCategory
+	}
+
+	var questions []models.Question
+	if err := DB.Model(&models.Question{}).
+		Scopes(CategoryScope("category", category)).
+		Where("id > ?", progress.LastQuestionID).
+		Order("id").
+		Limit(limit).
+		Find(&questions).Error; err != nil {
+		return nil, nil, err
+	}
+	return questions, progress, nil
+}
+
+// SaveSequentialPosition 将分类顺序练习的位置移到指定题目
+// advance为true时只向后移动，重做前面的题目不会使进度倒退
+func SaveSequentialPosition(userID uint, category string, questionID uint, advance bool) (*models.SequentialProgress, error) {
+	var count int64
+	if err := DB.Model(&models.Question{}).
+		Scopes(CategoryScope("category", category)).
+		Where("id = ?", questionID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrQuestionNotInCategory
+	}
+
+	position := models.PracticePosition{
+		UserID:         userID,
+		Category:       category,
+		LastQuestionID: questionID,
+		UpdatedAt:      time.Now(),
+	}
+	updates := clause.AssignmentColumns([]string{"last_question_id", "updated_at"})
+	if advance {
+		updates = clause.Set{
+			{Column: clause.Column{Name: "last_question_id"}, Value: gorm.Expr("max(last_question_id, excluded.last_question_id)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")},
+		}
+	}
+	if err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "category"}},
+		DoUpdates: updates,
+	}).Create(&position).Error; err != nil {
+		return nil, err
+	}
+	return sequentialProgress(userID, category)
+}
+
+// ResetSequentialPosition 清除分类顺序练习的位置
+func ResetSequentialPosition(userID uint, category string) error {
+	return DB.Where("user_id = ? AND category = ?", userID, category).
+		Delete(&models.PracticePosition{}).Error
+}
+
+// ListSequentialProgress 获取用户所有分类的顺序练习进度，最近练习的在前
+func ListSequentialProgress(userID uint) ([]models.SequentialProgress, error) {
+	var positions []models.PracticePosition
+	if err := DB.Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&positions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SequentialProgress, 0, len(positions))
+	for _, position := range positions {
+		progress, err := sequentialProgress(userID, position.Category)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *progress)
+	}
+	return result, nil
+}
+
+// sequentialProgress 计算分类顺序练习进度
+func sequentialProgress(userID uint, category string) (*models.SequentialProgress, error) {
+	progress := &models.SequentialProgress{Category: category}
+
+	var position models.PracticePosition
+	err := DB.Where("user_id = ? AND category = ?", userID, category).First(&position).Error
+	switch {
+	case err == nil:
+		progress.LastQuestionID = position.LastQuestionID
+		progress.UpdatedAt = &position.UpdatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	var counts struct {
+		Total     int64
+		Completed int64
+		MaxID     uint
+	}
+	if err := DB.Model(&models.Question{}).
+		Scopes(CategoryScope("category", category)).
+		Select("count(*) AS total, sum(case when id <= ? then 1 else 0 end) AS completed, coalesce(max(id), 0) AS max_id", progress.LastQuestionID).
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	progress.Total = counts.Total
+	progress.Completed = counts.Completed
+	progress.Finished = counts.Total > 0 && progress.LastQuestionID >= counts.MaxID
+	return progress, nil
+}
+
+// 自定义练习参数
+const (
+	CustomPracticeType      = "custom_practice"
+	defaultPracticeSetCount = 20
+)
+
+var (
+	// ErrNotEnoughQuestions 符合自定义练习条件的题目不足
+	ErrNotEnoughQuestions = errors.New("not enough questions match the practice filters")
+	// ErrConflictingPracticeFilters 只选未作答题目与只选错题不能同时使用
+	ErrConflictingPracticeFilters = errors.New("only_unseen cannot be combined with only_wrong")
+)
+
+// ValidatePracticeSet 校验自定义练习请求并填充默认值
+func ValidatePracticeSet(req *models.PracticeSetRequest) error {
+	if req.Count < 0 {
+		return errors.New("count cannot be negative")
+	}
+	if req.Count == 0 {
+		req.Count = defaultPracticeSetCount
+	}
+	if req.Count > maxBlueprintQuestions {
+		return fmt.Errorf("count cannot be more than %d", maxBlueprintQuestions)
+	}
+	if req.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+	if req.ExcludeRecentDays < 0 {
+		return errors.New("exclude_recent_days cannot be negative")
+	}
+	for _, t := range req.Types {
+		if questionTypeNames[t] == "" {
+			return fmt.Errorf("unknown question type %q", t)
+		}
+	}
+	if _, _, err := ParseDifficultyRange(req.Difficulty); err != nil {
+		return err
+	}
+	if req.OnlyUnseen && req.OnlyWrong {
+		return ErrConflictingPracticeFilters
+	}
+	req.Tags = NormalizeTags(req.Tags)
+	return nil
+}
+
+// practiceSetScope 自定义练习的筛选条件，请求需已通过ValidatePracticeSet校验
+func practiceSetScope(userID uint, req *models.PracticeSetRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(req.Categories) > 0 {
+			var categories []string
+			for _, category := range req.Categories {
+				expanded, err := ExpandCategory(category)
+				if err != nil {
+					db.AddError(err)
+					return db
+				}
+				categories = append(categories, expanded...)
+			}
+			db = db.Where("category IN ?", categories)
+		}
+		if len(req.Types) > 0 {
+			db = db.Where("type IN ?", req.Types)
+		}
+
+		minDifficulty, maxDifficulty, _ := ParseDifficultyRange(req.Difficulty)
+		db = QuestionFilter{Tags: req.Tags, MinDifficulty: minDifficulty, MaxDifficulty: maxDifficulty}.apply(db)
+
+		if req.OnlyUnseen {
+			db = db.Where("id NOT IN (?)", answeredQuestions(userID))
+		}
+		if req.ExcludeRecentDays > 0 {
+			since := time.Now().AddDate(0, 0, -req.ExcludeRecentDays)
+			db = db.Where("id NOT IN (?)", answeredQuestions(userID).Where("answered_at >= ?", since))
+		}
+		if req.OnlyWrong {
+			db = db.Where("id IN (?)", DB.Model(&models.WrongBookEntry{}).
+				Select("question_id").
+				Where("user_id = ? AND status = ?", userID, WrongStatusActive))
+		}
+		if req.OnlyBookmarked {
+			db = db.Where("id IN (?)", DB.Model(&models.Bookmark{}).
+				Select("question_id").
+				Where("user_id = ?", userID))
+		}
+		return db
+	}
+}
+
+// PreviewPracticeSet 统计符合自定义练习条件的题目数
+func PreviewPracticeSet(userID uint, req *models.PracticeSetRequest) (*models.PracticeSetPreview, error) {
+	var available int64
+	if err := DB.Model(&models.Question{}).
+		Scopes(practiceSetScope(userID, req)).
+		Count(&available).Error; err != nil {
+		return nil, err
+	}
+
+	preview := &models.PracticeSetPreview{
+		Available: available,
+		Count:     req.Count,
+		Enough:    available >= int64(req.Count),
+	}
+	if !preview.Enough && req.AllowFewer {
+		preview.Count = int(available)
+	}
+	return preview, nil
+}
+
+// SelectPracticeSet 按自定义练习条件随机抽题
+// 题目不足Count道且未设置AllowFewer，或没有任何符合条件的题目时返回ErrNotEnoughQuestions
+func SelectPracticeSet(userID uint, req *models.PracticeSetRequest) ([]models.Question, *models.PracticeSetPreview, error) {
+	preview, err := PreviewPracticeSet(userID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if preview.Available == 0 || (!preview.Enough && !req.AllowFewer) {
+		return nil, preview, ErrNotEnoughQuestions
+	}
+
+	var questions []models.Question
+	if err := DB.Model(&models.Question{}).
+		Scopes(practiceSetScope(userID, req)).
+		Order("RANDOM()").
+		Limit(preview.Count).
+		Find(&questions).Error; err != nil {
+		return nil, nil, err
+	}
+	return questions, preview, nil
+}
@@ -0,0 +1,648 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"quiz-system/models"
+)
+
+// QTI 2.1 相关常量
+const (
+	qtiNamespace       = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	imscpNamespace     = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	qtiItemResource    = "imsqti_item_xmlv2p1"
+	qtiTestResource    = "imsqti_test_xmlv2p1"
+	qtiDefaultCategory = "QTI导入"
+)
+
+// qtiItemIDPattern 导出时使用的题目标识，导入时据此还原题目ID
+var qtiItemIDPattern = regexp.MustCompile(`^q(\d+)$`)
+
+// qtiLetterPattern 单个大写字母的选项标识
+var qtiLetterPattern = regexp.MustCompile(`^[A-Z]$`)
+
+// qtiJudgeTexts 判断题选项文本
+var qtiJudgeTexts = map[string]bool{
+	"正确": true, "错误": true, "对": true, "错": true, "true": true, "false": true,
+}
+
+// ---------- 导入 ----------
+
+type qtiManifest struct {
+	Resources []struct {
+		Identifier string `xml:"identifier,attr"`
+		Type       string `xml:"type,attr"`
+		Href       string `xml:"href,attr"`
+	} `xml:"resources>resource"`
+}
+
+type qtiAssessmentTest struct {
+	Sections []qtiSection `xml:"testPart>assessmentSection"`
+}
+
+type qtiSection struct {
+	Title    string       `xml:"title,attr"`
+	ItemRefs []qtiItemRef `xml:"assessmentItemRef"`
+	Sections []qtiSection `xml:"assessmentSection"`
+}
+
+type qtiItemRef struct {
+	Href string `xml:"href,attr"`
+}
+
+type qtiAssessmentItem struct {
+	Identifier           string `xml:"identifier,attr"`
+	ResponseDeclarations []struct {
+		Identifier    string   `xml:"identifier,attr"`
+		Cardinality   string   `xml:"cardinality,attr"`
+		BaseType      string   `xml:"baseType,attr"`
+		CorrectValues []string `xml:"correctResponse>value"`
+	} `xml:"responseDeclaration"`
+	ItemBody struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"itemBody"`
+	ModalFeedbacks []struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"modalFeedback"`
+}
+
+type qtiChoiceInteraction struct {
+	ResponseIdentifier string `xml:"responseIdentifier,attr"`
+	Prompt             struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"prompt"`
+	Choices []struct {
+		Identifier string `xml:"identifier,attr"`
+		Inner      string `xml:",innerxml"`
+	} `xml:"simpleChoice"`
+}
+
+// parseQTI 解析QTI内容包（zip）或单个assessmentItem XML
+func parseQTI(data []byte) ([]QuestionData, error) {
+	if bytes.HasPrefix(data, []byte("PK")) {
+		return parseQTIPackage(data)
+	}
+
+	qd, err := parseQTIItem(data, qtiDefaultCategory)
+	if err != nil {
+		return nil, err
+	}
+	return []QuestionData{*qd}, nil
+}
+
+// parseQTIPackage 解析QTI内容包，按imsmanifest.xml定位题目，按assessmentTest分节确定分类
+func parseQTIPackage(data []byte) ([]QuestionData, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QTI package: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[path.Clean(f.Name)] = f
+	}
+
+	var itemHrefs, testHrefs []string
+	if manifestFile, ok := files["imsmanifest.xml"]; ok {
+		var manifest qtiManifest
+		if err := decodeZipXML(manifestFile, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid imsmanifest.xml: %v", err)
+		}
+		for _, res := range manifest.Resources {
+			switch {
+			case strings.HasPrefix(res.Type, "imsqti_item"):
+				itemHrefs = append(itemHrefs, path.Clean(res.Href))
+			case strings.HasPrefix(res.Type, "imsqti_test"):
+				testHrefs = append(testHrefs, path.Clean(res.Href))
+			}
+		}
+	} else {
+		// 无清单时尝试包内所有XML文件
+		for name := range files {
+			if strings.HasSuffix(strings.ToLower(name), ".xml") {
+				itemHrefs = append(itemHrefs, name)
+			}
+		}
+		sort.Strings(itemHrefs)
+	}
+
+	// 从测试结构中读取分节标题作为分类
+	categories := make(map[string]string)
+	for _, href := range testHrefs {
+		f, ok := files[href]
+		if !ok {
+			continue
+		}
+		var test qtiAssessmentTest
+		if err := decodeZipXML(f, &test); err != nil {
+			return nil, fmt.Errorf("invalid assessment test %s: %v", href, err)
+		}
+		collectQTISectionCategories(test.Sections, path.Dir(href), categories)
+	}
+
+	var questions []QuestionData
+	for _, href := range itemHrefs {
+		f, ok := files[href]
+		if !ok {
+			return nil, fmt.Errorf("QTI item %s not found in package", href)
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(content, []byte("assessmentItem")) {
+			continue
+		}
+
+		category := categories[href]
+		if category == "" {
+			category = qtiDefaultCategory
+		}
+		qd, err := parseQTIItem(content, category)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", href, err)
+		}
+		questions = append(questions, *qd)
+	}
+
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("no QTI items found in package")
+	}
+	return questions, nil
+}
+
+// collectQTISectionCategories 递归记录每个题目引用所在分节的标题
+func collectQTISectionCategories(sections []qtiSection, baseDir string, categories map[string]string) {
+	for _, section := range sections {
+		for _, ref := range section.ItemRefs {
+			categories[path.Clean(path.Join(baseDir, ref.Href))] = strings.TrimSpace(section.Title)
+		}
+		collectQTISectionCategories(section.Sections, baseDir, categories)
+	}
+}
+
+// parseQTIItem 将assessmentItem中的choiceInteraction转换为题目数据
+func parseQTIItem(data []byte, category string) (*QuestionData, error) {
+	var item qtiAssessmentItem
+	if err := xml.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("invalid assessmentItem: %v", err)
+	}
+
+	stem, interaction, err := parseQTIItemBody(item.ItemBody.Inner)
+	if err != nil {
+		return nil, err
+	}
+	if interaction == nil {
+		return nil, fmt.Errorf("item %s has no choiceInteraction", item.Identifier)
+	}
+
+	question := strings.TrimSpace(strings.Join([]string{stem, xmlText(interaction.Prompt.Inner)}, "\n"))
+	if question == "" {
+		return nil, fmt.Errorf("item %s has an empty question", item.Identifier)
+	}
+
+	// 找到交互对应的作答声明
+	cardinality, baseType := "single", "identifier"
+	var correct []string
+	for _, decl := range item.ResponseDeclarations {
+		if decl.Identifier == interaction.ResponseIdentifier {
+			cardinality, baseType, correct = decl.Cardinality, decl.BaseType, decl.CorrectValues
+			break
+		}
+	}
+	if len(correct) == 0 {
+		return nil, fmt.Errorf("item %s has no correct response", item.Identifier)
+	}
+
+	// 标识本身是单个字母时直接沿用，否则按顺序映射为A、B、C…
+	options := make(map[string]string, len(interaction.Choices))
+	letters := make(map[string]string, len(interaction.Choices))
+	judge := len(interaction.Choices) == 2
+	for i, choice := range interaction.Choices {
+		identifier := strings.TrimSpace(choice.Identifier)
+		letter := string(rune('A' + i))
+		if qtiLetterPattern.MatchString(identifier) {
+			letter = identifier
+		}
+		if _, exists := options[letter]; exists {
+			return nil, fmt.Errorf("item %s has duplicate choice %s", item.Identifier, identifier)
+		}
+		text := xmlText(choice.Inner)
+		options[letter] = text
+		letters[identifier] = letter
+		if !qtiJudgeTexts[strings.ToLower(text)] {
+			judge = false
+		}
+	}
+
+	var answer []string
+	for _, value := range correct {
+		letter, ok := letters[strings.TrimSpace(value)]
+		if !ok {
+			return nil, fmt.Errorf("item %s: correct response %q does not match any choice", item.Identifier, value)
+		}
+		answer = append(answer, letter)
+	}
+	sort.Strings(answer)
+
+	qType := "single"
+	switch {
+	case cardinality == "multiple":
+		qType = "multiple"
+	case judge || baseType == "boolean":
+		qType = "judge"
+	}
+
+	var explanations []string
+	for _, feedback := range item.ModalFeedbacks {
+		if text := xmlText(feedback.Inner); text != "" {
+			explanations = append(explanations, text)
+		}
+	}
+
+	qd := &QuestionData{
+		Type:        qType,
+		Question:    question,
+		Options:     options,
+		Answer:      strings.Join(answer, ""),
+		Category:    category,
+		Explanation: strings.Join(explanations, "\n"),
+	}
+	if m := qtiItemIDPattern.FindStringSubmatch(item.Identifier); m != nil {
+		qd.ID, _ = strconv.Atoi(m[1])
+	}
+	return qd, nil
+}
+
+// parseQTIItemBody 提取题干文本和第一个choiceInteraction（可嵌套在p、div等元素中）
+func parseQTIItemBody(inner string) (string, *qtiChoiceInteraction, error) {
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	var stem strings.Builder
+	var interaction *qtiChoiceInteraction
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid itemBody: %v", err)
+		}
+
+		if t, ok := token.(xml.StartElement); ok && t.Name.Local == "choiceInteraction" && interaction == nil {
+			interaction = &qtiChoiceInteraction{}
+			if err := decoder.DecodeElement(interaction, &t); err != nil {
+				return "", nil, fmt.Errorf("invalid choiceInteraction: %v", err)
+			}
+			continue
+		}
+		writeXMLText(&stem, token)
+	}
+
+	return strings.TrimSpace(stem.String()), interaction, nil
+}
+
+// xmlText 提取XML片段中的纯文本
+func xmlText(inner string) string {
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	var sb strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		writeXMLText(&sb, token)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// writeXMLText 输出文本节点，忽略排版产生的空白，段落和换行元素转换为换行符
+func writeXMLText(sb *strings.Builder, token xml.Token) {
+	switch t := token.(type) {
+	case xml.CharData:
+		if len(bytes.TrimSpace(t)) == 0 && bytes.ContainsRune(t, '\n') {
+			return
+		}
+		sb.Write(t)
+	case xml.StartElement:
+		if t.Name.Local == "br" {
+			sb.WriteString("\n")
+		}
+	case xml.EndElement:
+		switch t.Name.Local {
+		case "p", "div", "li":
+			sb.WriteString("\n")
+		}
+	}
+}
+
+// decodeZipXML 解析压缩包中的XML文件
+func decodeZipXML(f *zip.File, v interface{}) error {
+	content, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, v)
+}
+
+// readZipFile 读取压缩包中的文件内容，解压后超过maxZipEntrySize时报错，防止压缩炸弹耗尽内存
+func readZipFile(f *zip.File) ([]byte, error) {
+	tooLarge := fmt.Errorf("%s exceeds %d MB when uncompressed", f.Name, maxZipEntrySize>>20)
+	if f.UncompressedSize64 > maxZipEntrySize {
+		return nil, tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	// 压缩包中声明的大小可能与实际不符，读取时同样限制
+	content, err := io.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxZipEntrySize {
+		return nil, tooLarge
+	}
+	return content, nil
+}
+
+// ---------- 导出 ----------
+
+type qtiOutItem struct {
+	XMLName             xml.Name                 `xml:"assessmentItem"`
+	Xmlns               string                   `xml:"xmlns,attr"`
+	Identifier          string                   `xml:"identifier,attr"`
+	Title               string                   `xml:"title,attr"`
+	Adaptive            bool                     `xml:"adaptive,attr"`
+	TimeDependent       bool                     `xml:"timeDependent,attr"`
+	ResponseDeclaration qtiOutResponseDecl       `xml:"responseDeclaration"`
+	OutcomeDeclarations []qtiOutOutcomeDecl      `xml:"outcomeDeclaration"`
+	ItemBody            qtiOutItemBody           `xml:"itemBody"`
+	ResponseProcessing  qtiOutResponseProcessing `xml:"responseProcessing"`
+	ModalFeedback       *qtiOutModalFeedback     `xml:"modalFeedback,omitempty"`
+}
+
+type qtiOutResponseDecl struct {
+	Identifier  string   `xml:"identifier,attr"`
+	Cardinality string   `xml:"cardinality,attr"`
+	BaseType    string   `xml:"baseType,attr"`
+	Values      []string `xml:"correctResponse>value"`
+}
+
+type qtiOutOutcomeDecl struct {
+	Identifier  string `xml:"identifier,attr"`
+	Cardinality string `xml:"cardinality,attr"`
+	BaseType    string `xml:"baseType,attr"`
+}
+
+type qtiOutItemBody struct {
+	Interaction qtiOutChoiceInteraction `xml:"choiceInteraction"`
+}
+
+type qtiOutChoiceInteraction struct {
+	ResponseIdentifier string         `xml:"responseIdentifier,attr"`
+	Shuffle            bool           `xml:"shuffle,attr"`
+	MaxChoices         int            `xml:"maxChoices,attr"`
+	Prompt             string         `xml:"prompt"`
+	Choices            []qtiOutChoice `xml:"simpleChoice"`
+}
+
+type qtiOutChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Text       string `xml:",chardata"`
+}
+
+// qtiOutResponseProcessing 有解析时需自定义响应处理以设置FEEDBACK，否则使用标准模板
+type qtiOutResponseProcessing struct {
+	Template string `xml:"template,attr,omitempty"`
+	Inner    string `xml:",innerxml"`
+}
+
+type qtiOutModalFeedback struct {
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Text              string `xml:",chardata"`
+}
+
+type qtiOutManifest struct {
+	XMLName       xml.Name         `xml:"manifest"`
+	Xmlns         string           `xml:"xmlns,attr"`
+	Identifier    string           `xml:"identifier,attr"`
+	Schema        string           `xml:"metadata>schema"`
+	SchemaVersion string           `xml:"metadata>schemaversion"`
+	Organizations string           `xml:"organizations"`
+	Resources     []qtiOutResource `xml:"resources>resource"`
+}
+
+type qtiOutResource struct {
+	Identifier string `xml:"identifier,attr"`
+	Type       string `xml:"type,attr"`
+	Href       string `xml:"href,attr"`
+	File       struct {
+		Href string `xml:"href,attr"`
+	} `xml:"file"`
+	Dependencies []qtiOutDependency `xml:"dependency"`
+}
+
+type qtiOutDependency struct {
+	IdentifierRef string `xml:"identifierref,attr"`
+}
+
+type qtiOutTest struct {
+	XMLName    xml.Name `xml:"assessmentTest"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	Identifier string   `xml:"identifier,attr"`
+	Title      string   `xml:"title,attr"`
+	TestPart   struct {
+		Identifier     string              `xml:"identifier,attr"`
+		NavigationMode string              `xml:"navigationMode,attr"`
+		SubmissionMode string              `xml:"submissionMode,attr"`
+		Sections       []qtiOutTestSection `xml:"assessmentSection"`
+	} `xml:"testPart"`
+}
+
+type qtiOutTestSection struct {
+	Identifier string          `xml:"identifier,attr"`
+	Title      string          `xml:"title,attr"`
+	Visible    bool            `xml:"visible,attr"`
+	ItemRefs   []qtiOutItemRef `xml:"assessmentItemRef"`
+}
+
+type qtiOutItemRef struct {
+	Identifier string `xml:"identifier,attr"`
+	Href       string `xml:"href,attr"`
+}
+
+// qtiFeedbackProcessing 计分并始终显示解析的响应处理规则
+const qtiFeedbackProcessing = `<responseCondition><responseIf><match><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></match><setOutcomeValue identifier="SCORE"><baseValue baseType="float">1</baseValue></setOutcomeValue></responseIf><responseElse><setOutcomeValue identifier="SCORE"><baseValue baseType="float">0</baseValue></setOutcomeValue></responseElse></responseCondition><setOutcomeValue identifier="FEEDBACK"><baseValue baseType="identifier">EXPLANATION</baseValue></setOutcomeValue>`
+
+// exportQTI 导出为QTI 2.1内容包（zip），每个分类对应assessmentTest中的一个分节
+func exportQTI(w io.Writer, filter QuestionFilter) error {
+	zw := zip.NewWriter(w)
+
+	manifest := qtiOutManifest{
+		Xmlns:         imscpNamespace,
+		Identifier:    "MANIFEST-quiz-system",
+		Schema:        "QTIv2.1 Package",
+		SchemaVersion: "1.0.0",
+	}
+	test := qtiOutTest{Xmlns: qtiNamespace, Identifier: "test", Title: exportTitle(filter)}
+	test.TestPart.Identifier = "part1"
+	test.TestPart.NavigationMode = "nonlinear"
+	test.TestPart.SubmissionMode = "simultaneous"
+
+	testResource := qtiOutResource{Identifier: "test", Type: qtiTestResource, Href: "assessment.xml"}
+	testResource.File.Href = "assessment.xml"
+
+	err := eachQuestion(filter, "category, id", func(q *models.Question) error {
+		identifier := fmt.Sprintf("q%d", q.ID)
+		href := "items/" + identifier + ".xml"
+
+		// 无法表示为选择题的题目（如答案缺失）跳过，不影响整个内容包
+		item, err := buildQTIItem(q, identifier)
+		if err != nil {
+			log.Printf("Skipping question in QTI export: %v", err)
+			return nil
+		}
+		if err := writeZipXML(zw, href, item); err != nil {
+			return err
+		}
+
+		// 登记到清单和测试分节
+		res := qtiOutResource{Identifier: identifier, Type: qtiItemResource, Href: href}
+		res.File.Href = href
+		manifest.Resources = append(manifest.Resources, res)
+		testResource.Dependencies = append(testResource.Dependencies, qtiOutDependency{IdentifierRef: identifier})
+
+		sections := test.TestPart.Sections
+		if len(sections) == 0 || sections[len(sections)-1].Title != q.Category {
+			test.TestPart.Sections = append(sections, qtiOutTestSection{
+				Identifier: fmt.Sprintf("section%d", len(sections)+1),
+				Title:      q.Category,
+				Visible:    true,
+			})
+		}
+		section := &test.TestPart.Sections[len(test.TestPart.Sections)-1]
+		section.ItemRefs = append(section.ItemRefs, qtiOutItemRef{Identifier: identifier, Href: href})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest.Resources = append([]qtiOutResource{testResource}, manifest.Resources...)
+	if err := writeZipXML(zw, "assessment.xml", test); err != nil {
+		return err
+	}
+	if err := writeZipXML(zw, "imsmanifest.xml", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// buildQTIItem 将题目转换为assessmentItem
+func buildQTIItem(q *models.Question, identifier string) (*qtiOutItem, error) {
+	if q.Type == "ordering" || q.Type == "matching" {
+		return nil, fmt.Errorf("question %d: %s questions cannot be exported to QTI", q.ID, q.Type)
+	}
+	options := parseOptions(q.Options)
+	if len(options) == 0 {
+		return nil, fmt.Errorf("question %d has no options and cannot be exported to QTI", q.ID)
+	}
+
+	cardinality, maxChoices := "single", 1
+	if q.Type == "multiple" {
+		cardinality, maxChoices = "multiple", 0
+	}
+
+	// 正确答案按选项字母输出，忽略答案中的分隔符
+	var values []string
+	for letter := range answerLetterSet(q.Answer) {
+		key := string(letter)
+		if _, ok := options[key]; !ok {
+			return nil, fmt.Errorf("question %d: answer %q does not match any option", q.ID, q.Answer)
+		}
+		values = append(values, key)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("question %d has no valid answer", q.ID)
+	}
+	sort.Strings(values)
+
+	item := &qtiOutItem{
+		Xmlns:      qtiNamespace,
+		Identifier: identifier,
+		Title:      q.Category,
+		ResponseDeclaration: qtiOutResponseDecl{
+			Identifier:  "RESPONSE",
+			Cardinality: cardinality,
+			BaseType:    "identifier",
+			Values:      values,
+		},
+		OutcomeDeclarations: []qtiOutOutcomeDecl{
+			{Identifier: "SCORE", Cardinality: "single", BaseType: "float"},
+		},
+		ItemBody: qtiOutItemBody{
+			Interaction: qtiOutChoiceInteraction{
+				ResponseIdentifier: "RESPONSE",
+				MaxChoices:         maxChoices,
+				Prompt:             q.Question,
+			},
+		},
+		ResponseProcessing: qtiOutResponseProcessing{
+			Template: "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct",
+		},
+	}
+
+	for _, key := range sortedKeys(options) {
+		item.ItemBody.Interaction.Choices = append(item.ItemBody.Interaction.Choices, qtiOutChoice{
+			Identifier: key,
+			Text:       options[key],
+		})
+	}
+
+	if q.Explanation != "" {
+		item.OutcomeDeclarations = append(item.OutcomeDeclarations, qtiOutOutcomeDecl{
+			Identifier: "FEEDBACK", Cardinality: "single", BaseType: "identifier",
+		})
+		item.ResponseProcessing = qtiOutResponseProcessing{Inner: qtiFeedbackProcessing}
+		item.ModalFeedback = &qtiOutModalFeedback{
+			OutcomeIdentifier: "FEEDBACK",
+			Identifier:        "EXPLANATION",
+			ShowHide:          "show",
+			Text:              q.Explanation,
+		}
+	}
+
+	return item, nil
+}
+
+// writeZipXML 向压缩包写入带XML声明的文件
+func writeZipXML(zw *zip.Writer, name string, v interface{}) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(fw)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
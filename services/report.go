@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// 纠错报告状态
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+	ReportStatusRejected = "rejected"
+)
+
+// reportReasons 允许的报告原因
+var reportReasons = map[string]bool{
+	"wrong_answer":   true, // 答案错误
+	"wrong_question": true, // 题干或选项错误
+	"typo":           true, // 错别字
+	"outdated":       true, // 依据的标准已更新
+	"other":          true,
+}
+
+// 纠错报告相关错误
+var (
+	ErrInvalidReportReason = errors.New("invalid report reason")
+	ErrDuplicateReport     = errors.New("you already have an open report for this question")
+	ErrReportClosed        = errors.New("report has already been closed")
+)
+
+// CreateQuestionReport 提交题目纠错报告，同一用户对同一题目只能有一个待处理报告
+func CreateQuestionReport(userID, questionID uint, req *models.QuestionReportRequest) (*models.QuestionReport, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if !reportReasons[reason] {
+		return nil, ErrInvalidReportReason
+	}
+
+	var question models.Question
+	if err := DB.First(&question, questionID).Error; err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := DB.Model(&models.QuestionReport{}).
+		Where("user_id = ? AND question_id = ? AND status = ?", userID, questionID, ReportStatusOpen).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrDuplicateReport
+	}
+
+	report := models.QuestionReport{
+		QuestionID:      questionID,
+		UserID:          userID,
+		Reason:          reason,
+		SuggestedAnswer: strings.TrimSpace(req.SuggestedAnswer),
+		Comment:         strings.TrimSpace(req.Comment),
+		Revision:        question.Revision,
+		Status:          ReportStatusOpen,
+	}
+	if err := DB.Create(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetUserReports 获取用户提交的报告（新报告在前）
+func GetUserReports(userID uint, limit int) ([]models.QuestionReport, error) {
+	var reports []models.QuestionReport
+	if err := DB.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ListQuestionReports 获取待审核队列，按提交时间先后排列并附带题目当前内容
+func ListQuestionReports(status string, questionID uint, limit, offset int) ([]models.QuestionReportDetail, int64, error) {
+	query := DB.Model(&models.QuestionReport{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if questionID > 0 {
+		query = query.Where("question_id = ?", questionID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []models.QuestionReport
+	if err := query.Order("created_at, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&reports).Error; err != nil {
+		return nil, 0, err
+	}
+
+	details := make([]models.QuestionReportDetail, len(reports))
+	for i := range reports {
+		details[i].QuestionReport = reports[i]
+		if question, err := Cache.GetQuestion(reports[i].QuestionID); err == nil {
+			details[i].Question = question
+		}
+	}
+	return details, total, nil
+}
+
+// ResolveQuestionReport 处理纠错报告
+// 提供题目编辑时先编辑题目，再将该题所有待处理报告标记为已解决；否则只解决当前报告。
+// 每个报告的提交者都会收到通知
+func ResolveQuestionReport(reportID, adminID uint, req *models.ReportResolveRequest) ([]models.QuestionReport, *models.QuestionRevision, error) {
+	report, err := getOpenReport(reportID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var question *models.Question
+	var revision *models.QuestionRevision
+	if req.Update != nil {
+		if strings.TrimSpace(req.Update.ChangeNote) == "" {
+			req.Update.ChangeNote = fmt.Sprintf("处理纠错报告 #%d", report.ID)
+		}
+		if question, revision, err = UpdateQuestion(report.QuestionID, req.Update, adminID); err != nil {
+			return nil, nil, err
+		}
+	} else if question, err = Cache.GetQuestion(report.QuestionID); err != nil {
+		return nil, nil, err
+	}
+
+	var reports []models.QuestionReport
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("status = ?", ReportStatusOpen)
+		if revision != nil {
+			query = query.Where("question_id = ?", report.QuestionID)
+		} else {
+			query = query.Where("id = ?", report.ID)
+		}
+		if err := query.Find(&reports).Error; err != nil {
+			return err
+		}
+
+		for i := range reports {
+			message := fmt.Sprintf("你报告的题目 #%d 已确认，题目未作修改。", question.ID)
+			if revision != nil {
+				message = fmt.Sprintf("你报告的题目 #%d 已修正（版本 %d）。", question.ID, revision.Revision)
+			}
+			if err := closeReport(tx, &reports[i], ReportStatusResolved, adminID, question.Revision, req.Note); err != nil {
+				return err
+			}
+			if err := notifyReporter(tx, &reports[i], "report_resolved", "纠错报告已处理", message, req.Note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reports, revision, nil
+}
+
+// RejectQuestionReport 驳回纠错报告并通知提交者
+func RejectQuestionReport(reportID, adminID uint, note string) (*models.QuestionReport, error) {
+	report, err := getOpenReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := closeReport(tx, report, ReportStatusRejected, adminID, 0, note); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("你报告的题目 #%d 经核实无误，报告未被采纳。", report.QuestionID)
+		return notifyReporter(tx, report, "report_rejected", "纠错报告未被采纳", message, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// getOpenReport 获取待处理的报告
+func getOpenReport(reportID uint) (*models.QuestionReport, error) {
+	var report models.QuestionReport
+	if err := DB.First(&report, reportID).Error; err != nil {
+		return nil, err
+	}
+	if report.Status != ReportStatusOpen {
+		return nil, ErrReportClosed
+	}
+	return &report, nil
+}
+
+// closeReport 更新报告的处理结果
+func closeReport(tx *gorm.DB, report *models.QuestionReport, status string, adminID uint, revision int, note string) error {
+	now := time.Now()
+	report.Status = status
+	report.ResolvedBy = adminID
+	report.ResolvedRevision = revision
+	report.ResolutionNote = strings.TrimSpace(note)
+	report.ResolvedAt = &now
+	return tx.Save(report).Error
+}
+
+// notifyReporter 通知报告提交者处理结果
+func notifyReporter(tx *gorm.DB, report *models.QuestionReport, notificationType, title, message, note string) error {
+	if note = strings.TrimSpace(note); note != "" {
+		message += "处理说明：" + note
+	}
+	return CreateNotification(tx, &models.Notification{
+		UserID:     report.UserID,
+		Type:       notificationType,
+		Title:      title,
+		Message:    message,
+		QuestionID: report.QuestionID,
+	})
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// RegradeResult 重新判分结果
+type RegradeResult struct {
+	QuestionID uint `json:"question_id,omitempty"`
+	Checked    int  `json:"checked"` // 检查的答题记录数
+	Changed    int  `json:"changed"` // 判分结果发生变化的记录数
+}
+
+// RegradeJobStatus 全量重新判分任务状态
+type RegradeJobStatus struct {
+	Running    bool       `json:"running"`
+	Questions  int        `json:"questions"`
+	Checked    int        `json:"checked"`
+	Changed    int        `json:"changed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// 题目编辑相关错误
+var (
+	ErrNoQuestionChanges = errors.New("no changes to question")
+	ErrEmptyAnswer       = errors.New("answer cannot be empty")
+)
+
+var (
+	regradeJob   RegradeJobStatus
+	regradeJobMu sync.Mutex
+)
+
+// UpdateQuestion 编辑题目并生成新版本
+func UpdateQuestion(questionID uint, req *models.QuestionUpdateRequest, editorID uint) (*models.Question, *models.QuestionRevision, error) {
+	var question *models.Question
+	var revision *models.QuestionRevision
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		question, revision, err = updateQuestion(tx, questionID, req, editorID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	Cache.InvalidateQuestion(questionID)
+	InvalidateDuplicateClusters()
+	InvalidateCategoryTree()
+	return question, revision, nil
+}
+
+// updateQuestion 在事务中编辑题目并生成新版本，提交后需清除题目缓存、近似重复聚类和分类树
+func updateQuestion(tx *gorm.DB, questionID uint, req *models.QuestionUpdateRequest, editorID uint) (*models.Question, *models.QuestionRevision, error) {
+	var question models.Question
+	if err := tx.First(&question, questionID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// 首次编辑时为原始题目补一条基线版本
+	if err := ensureBaselineRevision(tx, &question); err != nil {
+		return nil, nil, err
+	}
+
+	changed := applyQuestionUpdate(&question, req)
+	if len(changed) == 0 {
+		return nil, nil, ErrNoQuestionChanges
+	}
+	if strings.TrimSpace(question.Answer) == "" {
+		return nil, nil, ErrEmptyAnswer
+	}
+	if question.Difficulty != 0 && !validDifficulty(question.Difficulty) {
+		return nil, nil, ErrInvalidDifficulty
+	}
+	grading, err := prepareGradingRule(question.Type, question.Answer, question.Grading)
+	if err != nil {
+		return nil, nil, err
+	}
+	question.Grading = grading
+	if err := validateStructuredAnswer(question.Type, parseOptions(question.Options), question.Answer); err != nil {
+		return nil, nil, err
+	}
+	if err := checkAttachmentRefs(tx, questionAttachmentRefs(&question)); err != nil {
+		return nil, nil, err
+	}
+
+	question.Revision++
+	if err := tx.Save(&question).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := saveQuestionTags(tx, question.ID, question.Tags); err != nil {
+		return nil, nil, err
+	}
+	if err := indexQuestion(tx, &question); err != nil {
+		return nil, nil, err
+	}
+
+	revision := newRevision(&question)
+	revision.ChangedFields = strings.Join(changed, ",")
+	revision.ChangeNote = strings.TrimSpace(req.ChangeNote)
+	revision.EditorID = editorID
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, nil, err
+	}
+	return &question, &revision, nil
+}
+
+// GetQuestionChangelog 获取题目的版本历史（新版本在前）
+func GetQuestionChangelog(questionID uint) ([]models.QuestionRevision, error) {
+	var question models.Question
+	if err := DB.First(&question, questionID).Error; err != nil {
+		return nil, err
+	}
+
+	var revisions []models.QuestionRevision
+	if err := DB.Where("question_id = ?", questionID).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	// 从未编辑过的题目只有当前版本
+	if len(revisions) == 0 {
+		current := newRevision(&question)
+		current.CreatedAt = question.CreatedAt
+		revisions = append(revisions, current)
+	}
+
+	return revisions, nil
+}
+
+// RegradeQuestion 按题目当前答案重新判定历史答题记录
+func RegradeQuestion(questionID uint) (*RegradeResult, error) {
+	result := &RegradeResult{QuestionID: questionID}
+	examRecords := make(map[uint]bool)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 在同一事务中读取和更新，避免期间新增或复核的记录被漏判或覆盖；已人工复核的记录保留复核结果
+		var question models.Question
+		if err := tx.First(&question, questionID).Error; err != nil {
+			return err
+		}
+		var answers []models.UserAnswer
+		if err := tx.Where("question_id = ? AND coalesce(revision, 0) < ? AND review_status <> ?",
+			questionID, question.Revision, ReviewStatusReviewed).
+			Find(&answers).Error; err != nil {
+			return err
+		}
+
+		for _, answer := range answers {
+			grade := GradeAnswer(&question, answer.UserAnswer)
+			if grade.IsCorrect != answer.IsCorrect || grade.Score != answer.Score {
+				result.Changed++
+				if answer.ExamRecordID != 0 {
+					examRecords[answer.ExamRecordID] = true
+				}
+			}
+			if err := tx.Model(&models.UserAnswer{}).
+				Where("id = ?", answer.ID).
+				Updates(map[string]interface{}{
+					"is_correct":    grade.IsCorrect,
+					"score":         grade.Score,
+					"review_status": grade.ReviewStatus,
+					"revision":      question.Revision,
+				}).Error; err != nil {
+				return err
+			}
+			result.Checked++
+		}
+		for recordID := range examRecords {
+			if err := recomputeExamRecord(tx, recordID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// StartRegradeJob 后台重新判分所有依据旧版本判分的答题记录
+func StartRegradeJob() error {
+	regradeJobMu.Lock()
+	if regradeJob.Running {
+		regradeJobMu.Unlock()
+		return fmt.Errorf("regrade job already running")
+	}
+	now := time.Now()
+	regradeJob = RegradeJobStatus{Running: true, StartedAt: &now}
+	regradeJobMu.Unlock()
+
+	go func() {
+		err := runRegradeJob()
+
+		regradeJobMu.Lock()
+		defer regradeJobMu.Unlock()
+		finished := time.Now()
+		regradeJob.Running = false
+		regradeJob.FinishedAt = &finished
+		if err != nil {
+			regradeJob.Error = err.Error()
+			log.Printf("Regrade job failed: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// GetRegradeJobStatus 获取重新判分任务状态
+func GetRegradeJobStatus() RegradeJobStatus {
+	regradeJobMu.Lock()
+	defer regradeJobMu.Unlock()
+	return regradeJob
+}
+
+// runRegradeJob 逐题重新判分
+func runRegradeJob() error {
+	var questionIDs []uint
+	if err := DB.Table("user_answers ua").
+		Joins("JOIN questions q ON ua.question_id = q.id").
+		Where("coalesce(ua.revision, 0) < q.revision").
+		Distinct("ua.question_id").
+		Pluck("ua.question_id", &questionIDs).Error; err != nil {
+		return err
+	}
+
+	for _, questionID := range questionIDs {
+		result, err := RegradeQuestion(questionID)
+		if err != nil {
+			return fmt.Errorf("question %d: %v", questionID, err)
+		}
+
+		regradeJobMu.Lock()
+		regradeJob.Questions++
+		regradeJob.Checked += result.Checked
+		regradeJob.Changed += result.Changed
+		regradeJobMu.Unlock()
+	}
+
+	return nil
+}
+
+// ensureBaselineRevision 确保题目已有当前版本的快照
+func ensureBaselineRevision(tx *gorm.DB, question *models.Question) error {
+	var count int64
+	if err := tx.Model(&models.QuestionRevision{}).
+		Where("question_id = ?", question.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if question.Revision < 1 {
+		question.Revision = 1
+	}
+	baseline := newRevision(question)
+	baseline.ChangeNote = "初始版本"
+	if !question.CreatedAt.IsZero() {
+		baseline.CreatedAt = question.CreatedAt
+	}
+	return tx.Create(&baseline).Error
+}
+
+// applyQuestionUpdate 将编辑请求应用到题目，返回发生变化的字段
+func applyQuestionUpdate(question *models.Question, req *models.QuestionUpdateRequest) []string {
+	var changed []string
+
+	if req.Type != nil {
+		if qType := normalizeQuestionType(*req.Type); qType != question.Type {
+			question.Type = qType
+			changed = append(changed, "type")
+		}
+	}
+	if req.Question != nil {
+		if text := strings.TrimSpace(*req.Question); text != "" && text != question.Question {
+			question.Question = text
+			changed = append(changed, "question")
+		}
+	}
+	if req.Options != nil {
+		if options := formatOptions(req.Options); options != question.Options {
+			question.Options = options
+			changed = append(changed, "options")
+		}
+	}
+	if req.Answer != nil {
+		if answer := strings.TrimSpace(*req.Answer); answer != question.Answer {
+			question.Answer = answer
+			changed = append(changed, "answer")
+		}
+	}
+	if req.Category != nil {
+		if category := strings.TrimSpace(*req.Category); category != "" && category != question.Category {
+			question.Category = category
+			changed = append(changed, "category")
+		}
+	}
+	if req.Explanation != nil {
+		if explanation := strings.TrimSpace(*req.Explanation); explanation != question.Explanation {
+			question.Explanation = explanation
+			changed = append(changed, "explanation")
+		}
+	}
+	if req.Tags != nil {
+		if tags := NormalizeTags(req.Tags); !tagsEqual(tags, NormalizeTags(question.Tags)) {
+			question.Tags = tags
+			changed = append(changed, "tags")
+		}
+	}
+	if req.Difficulty != nil && *req.Difficulty != question.Difficulty {
+		question.Difficulty = *req.Difficulty
+		changed = append(changed, "difficulty")
+	}
+	if req.Grading != nil && !gradingEqual(req.Grading, question.Grading) {
+		question.Grading = req.Grading
+		changed = append(changed, "grading")
+	}
+
+	return changed
+}
+
+// newRevision 根据题目当前内容生成版本快照
+func newRevision(question *models.Question) models.QuestionRevision {
+	return models.QuestionRevision{
+		QuestionID:  question.ID,
+		Revision:    question.Revision,
+		Type:        question.Type,
+		Question:    question.Question,
+		Options:     question.Options,
+		Answer:      question.Answer,
+		Category:    question.Category,
+		Explanation: question.Explanation,
+		Tags:        question.Tags,
+		Difficulty:  question.Difficulty,
+		Grading:     question.Grading,
+		CreatedAt:   time.Now(),
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// SM-2算法参数
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+	passingQuality    = 3 // 回忆质量达到该值视为记住
+	maxQuality        = 5
+)
+
+// ErrInvalidQuality 自评回忆质量超出范围
+var ErrInvalidQuality = errors.New("quality must be between 0 and 5")
+
+// answerQuality 按判分结果推断回忆质量：答对记4，答错时按得分记0-2，未作答记0
+func answerQuality(answer *models.UserAnswer) int {
+	switch {
+	case answer.IsCorrect:
+		return 4
+	case answer.UserAnswer == "":
+		return 0
+	case answer.Score >= 0.5:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// reviewQuality 结合自评与判分结果确定回忆质量：答对时自评不低于3，答错时不超过2
+func reviewQuality(answer *models.UserAnswer, quality *int) (int, error) {
+	if quality == nil {
+		return answerQuality(answer), nil
+	}
+	q := *quality
+	if q < 0 || q > maxQuality {
+		return 0, ErrInvalidQuality
+	}
+	if answer.IsCorrect && q < passingQuality {
+		q = passingQuality
+	}
+	if !answer.IsCorrect && q >= passingQuality {
+		q = passingQuality - 1
+	}
+	return q, nil
+}
+
+// scheduleReview 按答题记录更新复习计划，需在保存答题记录的事务中调用
+// 等待人工复核的记录不更新，复核后再计入；未到期时答对不延长间隔，答错仍视为遗忘
+func scheduleReview(tx *gorm.DB, answer *models.UserAnswer, quality int) (*models.ReviewCard, error) {
+	if answer.ReviewStatus == ReviewStatusPending {
+		return nil, nil
+	}
+
+	var card models.ReviewCard
+	err := tx.Where("user_id = ? AND question_id = ?", answer.UserID, answer.QuestionID).First(&card).Error
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !isNew {
+		return nil, err
+	}
+
+	now := answer.AnsweredAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if isNew {
+		card = models.ReviewCard{
+			UserID:     answer.UserID,
+			QuestionID: answer.QuestionID,
+			EaseFactor: defaultEaseFactor,
+		}
+	} else if quality >= passingQuality && now.Before(card.DueAt) {
+		return &card, nil
+	}
+
+	applySM2(&card, quality, now)
+	card.Category = answer.Category
+	if err := tx.Save(&card).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// applySM2 按SM-2算法更新记忆状态：记住时间隔依次为1天、6天，之后乘以难易度因子；遗忘时重新从1天开始
+func applySM2(card *models.ReviewCard, quality int, now time.Time) {
+	if quality >= passingQuality {
+		card.Repetitions++
+		switch card.Repetitions {
+		case 1:
+			card.Interval = 1
+		case 2:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.EaseFactor))
+		}
+	} else {
+		if !card.LastReviewedAt.IsZero() {
+			card.Lapses++
+		}
+		card.Repetitions = 0
+		card.Interval = 1
+	}
+
+	diff := float64(maxQuality - quality)
+	card.EaseFactor = math.Round((card.EaseFactor+0.1-diff*(0.08+diff*0.02))*100) / 100
+	if card.EaseFactor < minEaseFactor {
+		card.EaseFactor = minEaseFactor
+	}
+
+	card.LastQuality = quality
+	card.LastReviewedAt = now
+	card.DueAt = now.AddDate(0, 0, card.Interval)
+}
+
+// GetDueReviews 获取已到期的复习题目，最早到期的在前；category可为分类树父节点
+func GetDueReviews(userID uint, category string, limit int) ([]models.ReviewCard, []models.Question, error) {
+	var cards []models.ReviewCard
+	if err := DB.Where("user_id = ? AND due_at <= ?", userID, time.Now()).
+		Scopes(CategoryScope("category", category)).
+		Order("due_at, id").
+		Limit(limit).
+		Find(&cards).Error; err != nil {
+		return nil, nil, err
+	}
+
+	questions := make([]models.Question, 0, len(cards))
+	found := cards[:0]
+	for _, card := range cards {
+		question, err := Cache.GetQuestion(card.QuestionID)
+		if err != nil {
+			continue
+		}
+		questions = append(questions, *question)
+		found = append(found, card)
+	}
+	return found, questions, nil
+}
+
+// GetReviewSummary 统计复习队列的到期情况
+func GetReviewSummary(userID uint, category string) (*models.ReviewSummary, error) {
+	now := time.Now()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+
+	var summary models.ReviewSummary
+	if err := DB.Model(&models.ReviewCard{}).
+		Scopes(CategoryScope("category", category)).
+		Where("user_id = ?", userID).
+		Select("count(*) AS total, "+
+			"coalesce(sum(case when due_at <= ? then 1 else 0 end), 0) AS due, "+
+			"coalesce(sum(case when due_at < ? then 1 else 0 end), 0) AS due_today", now, endOfDay).
+		Scan(&summary).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// SaveReviewAnswer 保存复习模式的答题记录，按自评回忆质量更新复习计划，并更新其他学习记录
+func SaveReviewAnswer(userID uint, question *models.Question, userAnswer string, grade models.GradeResult, quality *int) (*models.UserAnswer, *models.ReviewCard, error) {
+	record := newUserAnswer(userID, question, userAnswer, grade, 0)
+	q, err := reviewQuality(record, quality)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var card *models.ReviewCard
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		card, err = trackAnswer(tx, record, q)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, card, nil
+}
+
+// replayGradedAnswers 按答题时间顺序分批回放已评分的答题记录
+func replayGradedAnswers(fn func(answer *models.UserAnswer)) error {
+	const batchSize = 1000
+
+	// FindInBatches按主键翻页，会漏掉时间靠后但主键较小的记录，这里按(answered_at, id)翻页
+	var lastAt time.Time
+	var lastID uint
+	for first := true; ; first = false {
+		var answers []models.UserAnswer
+		query := DB.Where("review_status <> ?", ReviewStatusPending)
+		if !first {
+			query = query.Where("answered_at > ? OR (answered_at = ? AND id > ?)", lastAt, lastAt, lastID)
+		}
+		if err := query.Order("answered_at, id").Limit(batchSize).Find(&answers).Error; err != nil {
+			return err
+		}
+		for i := range answers {
+			fn(&answers[i])
+		}
+		if len(answers) < batchSize {
+			return nil
+		}
+		last := answers[len(answers)-1]
+		lastAt, lastID = last.AnsweredAt, last.ID
+	}
+}
+
+// backfillReviewCards 复习计划为空时，按时间顺序回放已有答题记录生成复习计划
+func backfillReviewCards() error {
+	var cardCount, answerCount int64
+	if err := DB.Model(&models.ReviewCard{}).Count(&cardCount).Error; err != nil {
+		return err
+	}
+	if cardCount > 0 {
+		return nil
+	}
+	if err := DB.Model(&models.UserAnswer{}).Where("review_status <> ?", ReviewStatusPending).Count(&answerCount).Error; err != nil {
+		return err
+	}
+	if answerCount == 0 {
+		return nil
+	}
+
+	type cardKey struct{ userID, questionID uint }
+	cards := make(map[cardKey]*models.ReviewCard)
+	err := replayGradedAnswers(func(answer *models.UserAnswer) {
+		key := cardKey{answer.UserID, answer.QuestionID}
+		card, ok := cards[key]
+		quality := answerQuality(answer)
+		if !ok {
+			card = &models.ReviewCard{UserID: answer.UserID, QuestionID: answer.QuestionID, EaseFactor: defaultEaseFactor}
+			cards[key] = card
+		} else if quality >= passingQuality && answer.AnsweredAt.Before(card.DueAt) {
+			return
+		}
+		applySM2(card, quality, answer.AnsweredAt)
+		card.Category = answer.Category
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*models.ReviewCard, 0, len(cards))
+	for _, card := range cards {
+		batch = append(batch, card)
+	}
+	if err := DB.CreateInBatches(batch, 500).Error; err != nil {
+		return err
+	}
+	log.Printf("Built review schedule for %d questions from %d answers", len(batch), answerCount)
+	return nil
+}
@@ -0,0 +1,562 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// 搜索引擎类型
+const (
+	SearchEngineFTS  = "fts5"
+	SearchEngineLike = "like"
+)
+
+// searchTable FTS5全文索引表，rowid为题目ID，各列存储分词后的文本
+const searchTable = "question_fts"
+
+// searchWeights bm25中题干、选项、解析的权重
+const searchWeights = "10.0, 4.0, 1.0"
+
+// snippetWidth 高亮片段的最大字数
+const snippetWidth = 80
+
+// searchIndexReady FTS5全文索引是否可用；SQLite未编译FTS5时搜索退回LIKE查询
+var searchIndexReady bool
+
+var (
+	// ErrEmptySearchQuery 搜索表达式没有可匹配的词
+	ErrEmptySearchQuery = errors.New("search query must contain at least one term to match")
+	// ErrInvalidCursor 翻页游标无效或不属于当前搜索条件
+	ErrInvalidCursor = errors.New("invalid or expired cursor")
+)
+
+// searchQuery 解析后的搜索表达式：any中任意一组的词全部命中，且不含exclude中的词
+type searchQuery struct {
+	any     [][]string
+	exclude []string
+}
+
+// SearchEngine 返回当前使用的搜索引擎
+func SearchEngine() string {
+	if searchIndexReady {
+		return SearchEngineFTS
+	}
+	return SearchEngineLike
+}
+
+// initSearchIndex 创建FTS5全文索引，索引题目数与题库不一致时重建
+func initSearchIndex() error {
+	err := DB.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + searchTable +
+		" USING fts5(question, options, explanation, tokenize = 'unicode61 remove_diacritics 2')").Error
+	if err != nil {
+		log.Printf("Warning: SQLite FTS5 is not available (build with -tags sqlite_fts5), search falls back to LIKE: %v", err)
+		return nil
+	}
+	searchIndexReady = true
+
+	var indexed, total int64
+	if err := DB.Table(searchTable).Count(&indexed).Error; err != nil {
+		return err
+	}
+	if err := DB.Model(&models.Question{}).Count(&total).Error; err != nil {
+		return err
+	}
+	if indexed == total {
+		return nil
+	}
+
+	log.Printf("Rebuilding search index for %d questions...", total)
+	return rebuildSearchIndex()
+}
+
+// rebuildSearchIndex 重建全部题目的全文索引
+func rebuildSearchIndex() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + searchTable).Error; err != nil {
+			return err
+		}
+
+		var questions []models.Question
+		return tx.Model(&models.Question{}).FindInBatches(&questions, 500, func(batch *gorm.DB, _ int) error {
+			for i := range questions {
+				if err := insertSearchIndex(tx, &questions[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// indexQuestion 更新题目的全文索引，需在写入题目的事务中调用
+func indexQuestion(tx *gorm.DB, q *models.Question) error {
+	if !searchIndexReady {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM "+searchTable+" WHERE rowid = ?", q.ID).Error; err != nil {
+		return err
+	}
+	return insertSearchIndex(tx, q)
+}
+
+// insertSearchIndex 写入题目的分词文本
+func insertSearchIndex(tx *gorm.DB, q *models.Question) error {
+	return tx.Exec("INSERT INTO "+searchTable+" (rowid, question, options, explanation) VALUES (?, ?, ?, ?)",
+		q.ID, searchIndexText(q.Question), searchIndexText(searchOptionsText(q.Options)), searchIndexText(q.Explanation)).Error
+}
+
+// searchOptionsText 选项文本，去掉选项字母
+func searchOptionsText(optionsJSON string) string {
+	options := parseOptions(optionsJSON)
+	texts := make([]string, 0, len(options))
+	for _, key := range sortedKeys(options) {
+		texts = append(texts, options[key])
+	}
+	return strings.Join(texts, "\n")
+}
+
+// searchIndexText 将文本转换为索引用的分词文本，附件引用不参与索引
+func searchIndexText(s string) string {
+	return strings.Join(searchTokens(attachmentRefPattern.ReplaceAllString(s, " "), false), " ")
+}
+
+// searchTokens 中文按字二元切分，其他文字按词切分并转为小写
+// 索引时每段中文末尾再补一个单字，使单字查询可以用前缀匹配；查询时不补
+func searchTokens(s string, query bool) []string {
+	var tokens []string
+	var cjk []rune
+	var word []rune
+
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+			if !query {
+				tokens = append(tokens, string(cjk[len(cjk)-1]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range s {
+		r = foldWidth(r)
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushCJK()
+			flushWord()
+		}
+	}
+	flushCJK()
+	flushWord()
+	return tokens
+}
+
+// isCJK 是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// parseSearchQuery 解析搜索表达式
+// 空格分隔的词须同时命中，OR 连接任选其一，"..." 为短语，-词 或 NOT 词 表示排除
+func parseSearchQuery(s string) (*searchQuery, error) {
+	query := &searchQuery{}
+	var group []string
+	negate := false
+
+	for _, field := range splitSearchFields(s) {
+		if !field.quoted {
+			switch field.text {
+			case "OR":
+				if len(group) > 0 {
+					query.any = append(query.any, group)
+					group = nil
+				}
+				continue
+			case "AND":
+				continue
+			case "NOT":
+				negate = true
+				continue
+			}
+		}
+
+		if len(searchTokens(field.text, true)) > 0 {
+			if negate || field.negated {
+				query.exclude = append(query.exclude, field.text)
+			} else {
+				group = append(group, field.text)
+			}
+		}
+		negate = false
+	}
+	if len(group) > 0 {
+		query.any = append(query.any, group)
+	}
+	if len(query.any) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	return query, nil
+}
+
+// searchField 搜索表达式中的一项
+type searchField struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+// splitSearchFields 按空白切分搜索表达式，双引号内为一项（全角引号亦可），前缀"-"表示排除
+func splitSearchFields(s string) []searchField {
+	isQuote := func(r rune) bool { return r == '"' || r == '“' || r == '”' }
+
+	var fields []searchField
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		field := searchField{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			field.negated = true
+			i++
+		}
+
+		start := i
+		if isQuote(runes[i]) {
+			field.quoted = true
+			i++
+			start = i
+			for i < len(runes) && !isQuote(runes[i]) {
+				i++
+			}
+			field.text = strings.TrimSpace(string(runes[start:i]))
+			i++ // 跳过右引号
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			field.text = string(runes[start:i])
+		}
+		if field.text != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// ftsMatch 转换为FTS5 MATCH表达式
+func (q *searchQuery) ftsMatch() string {
+	groups := make([]string, 0, len(q.any))
+	for _, group := range q.any {
+		phrases := make([]string, len(group))
+		for i, term := range group {
+			phrases[i] = ftsPhrase(term)
+		}
+		groups = append(groups, strings.Join(phrases, " AND "))
+	}
+
+	expr := "(" + strings.Join(groups, " OR ") + ")"
+	for _, term := range q.exclude {
+		expr += " NOT " + ftsPhrase(term)
+	}
+	return expr
+}
+
+// ftsPhrase 将查询词转换为FTS5短语，单个汉字按前缀匹配
+func ftsPhrase(term string) string {
+	tokens := searchTokens(term, true)
+	phrase := `"` + strings.Join(tokens, " ") + `"`
+	if len(tokens) == 1 && len([]rune(tokens[0])) == 1 && isCJK([]rune(tokens[0])[0]) {
+		phrase += "*"
+	}
+	return phrase
+}
+
+// likeScope 转换为题目的LIKE查询条件，用于FTS5不可用时
+func (q *searchQuery) likeScope(query *gorm.DB) *gorm.DB {
+	return q.likeColumns(query, "question", "options", "explanation")
+}
+
+// likeColumns 转换为在指定列上的LIKE查询条件
+func (q *searchQuery) likeColumns(query *gorm.DB, columns ...string) *gorm.DB {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " LIKE ?"
+	}
+	condition := strings.Join(conditions, " OR ")
+	match := func(db *gorm.DB, term string) *gorm.DB {
+		pattern := "%" + term + "%"
+		args := make([]interface{}, len(columns))
+		for i := range args {
+			args[i] = pattern
+		}
+		return db.Where(condition, args...)
+	}
+
+	var anyGroup *gorm.DB
+	for _, group := range q.any {
+		all := DB.Session(&gorm.Session{NewDB: true})
+		for _, term := range group {
+			all = all.Where(match(DB.Session(&gorm.Session{NewDB: true}), term))
+		}
+		if anyGroup == nil {
+			anyGroup = DB.Session(&gorm.Session{NewDB: true}).Where(all)
+		} else {
+			anyGroup = anyGroup.Or(all)
+		}
+	}
+	query = query.Where(anyGroup)
+	for _, term := range q.exclude {
+		query = query.Not(match(DB.Session(&gorm.Session{NewDB: true}), term))
+	}
+	return query
+}
+
+// terms 需要高亮的查询词
+func (q *searchQuery) terms() []string {
+	var terms []string
+	for _, group := range q.any {
+		terms = append(terms, group...)
+	}
+	return terms
+}
+
+// searchCursor 搜索翻页游标，记录上一页最后一条结果的排序键和查询指纹
+type searchCursor struct {
+	Rank  float64 `json:"r"`
+	ID    uint    `json:"i"`
+	Query uint64  `json:"q"`
+}
+
+// encodeSearchCursor 编码翻页游标
+func encodeSearchCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSearchCursor 解码翻页游标，游标须属于同一搜索条件
+func decodeSearchCursor(s string, fingerprint uint64) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Query != fingerprint {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// searchFingerprint 搜索条件指纹，防止游标用于其他搜索
+func searchFingerprint(keyword string, filter QuestionFilter) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%+v", keyword, filter)
+	return h.Sum64()
+}
+
+// matchScope 限定为命中搜索表达式的题目，FTS5可用时关联出相关度hits.rank
+func (q *searchQuery) matchScope(query *gorm.DB) *gorm.DB {
+	if !searchIndexReady {
+		return q.likeScope(query)
+	}
+	hits := DB.Table(searchTable).
+		Select("rowid, bm25("+searchTable+", "+searchWeights+") AS rank").
+		Where(searchTable+" MATCH ?", q.ftsMatch())
+	return query.Joins("JOIN (?) AS hits ON hits.rowid = questions.id", hits)
+}
+
+// SearchQuestions 全文搜索题目，FTS5可用时按相关度排序，否则按LIKE匹配并按ID排序
+// cursor为上一页返回的next_cursor，为空时从第一页开始；结果附带命中总数和分类、题型分面计数
+func SearchQuestions(keyword string, filter QuestionFilter, limit int, cursor string) (*models.SearchPage, error) {
+	parsed, err := parseSearchQuery(keyword)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := searchFingerprint(keyword, filter)
+	var after *searchCursor
+	if cursor != "" {
+		if after, err = decodeSearchCursor(cursor, fingerprint); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &models.SearchPage{Questions: []models.SearchHit{}}
+	if err := DB.Model(&models.Question{}).
+		Scopes(filter.Scope, parsed.matchScope).
+		Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+	if page.Facets, err = searchFacets(parsed, filter); err != nil {
+		return nil, err
+	}
+
+	query := DB.Model(&models.Question{}).Scopes(filter.Scope, parsed.matchScope)
+	if searchIndexReady {
+		query = query.Select("questions.*, hits.rank").Order("hits.rank, questions.id")
+		if after != nil {
+			query = query.Where("hits.rank > ? OR (hits.rank = ? AND questions.id > ?)", after.Rank, after.Rank, after.ID)
+		}
+	} else {
+		query = query.Select("questions.*").Order("questions.id")
+		if after != nil {
+			query = query.Where("questions.id > ?", after.ID)
+		}
+	}
+
+	// 多取一条判断是否还有下一页
+	if err := query.Limit(limit + 1).Find(&page.Questions).Error; err != nil {
+		return nil, err
+	}
+	if len(page.Questions) > limit {
+		page.Questions = page.Questions[:limit]
+		last := page.Questions[limit-1]
+		page.NextCursor = encodeSearchCursor(searchCursor{Rank: last.Rank, ID: last.ID, Query: fingerprint})
+	}
+
+	terms := parsed.terms()
+	for i := range page.Questions {
+		hit := &page.Questions[i]
+		hit.Options = strings.Join(parseOptionList(hit.Options), "|")
+		snippets := make(map[string]string)
+		for field, text := range map[string]string{
+			"question":    hit.Question.Question,
+			"options":     hit.Options,
+			"explanation": hit.Explanation,
+		} {
+			if snippet := highlightSnippet(text, terms); snippet != "" {
+				snippets[field] = snippet
+			}
+		}
+		if len(snippets) > 0 {
+			hit.Snippets = snippets
+		}
+	}
+	return page, nil
+}
+
+// searchFacets 统计命中题目的分类和题型分布
+// 每个分面的计数不受该分面自身筛选条件的限制，便于在已选分类下切换到其他分类
+func searchFacets(parsed *searchQuery, filter QuestionFilter) (map[string][]models.SearchFacet, error) {
+	byCategory := filter
+	byCategory.Category = ""
+	byType := filter
+	byType.Type = ""
+
+	facets := make(map[string][]models.SearchFacet, 2)
+	for name, f := range map[string]QuestionFilter{"category": byCategory, "type": byType} {
+		column := "questions." + name
+		counts := []models.SearchFacet{}
+		if err := DB.Model(&models.Question{}).
+			Scopes(f.Scope, parsed.matchScope).
+			Select(column + " AS value, count(*) AS count").
+			Group(column).
+			Order("count DESC, value").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		facets[name] = counts
+	}
+	return facets, nil
+}
+
+// highlightSnippet 截取首个命中词附近的片段，命中词以<mark>标记，其余文本做HTML转义；未命中时返回空串
+func highlightSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := foldSearchText(runes)
+
+	// 标记命中的字符
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		needle := foldSearchText([]rune(term))
+		if len(needle) == 0 || len(needle) > len(lower) {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) != string(needle) {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := first - snippetWidth/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWidth
+	if end > len(runes) {
+		end = len(runes)
+	}
+	// 不在命中词中间截断
+	for end < len(runes) && marked[end-1] && marked[end] {
+		end++
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			sb.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			sb.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+// foldSearchText 逐字转为半角小写，字数不变，用于定位命中词
+func foldSearchText(runes []rune) []rune {
+	result := make([]rune, len(runes))
+	for i, r := range runes {
+		result[i] = unicode.ToLower(foldWidth(r))
+	}
+	return result
+}
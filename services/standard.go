@@ -0,0 +1,443 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// ErrStandardExists 标准号已存在
+var ErrStandardExists = errors.New("standard code already exists")
+
+// ErrDuplicateClause 同一标准中条款编号重复
+var ErrDuplicateClause = errors.New("duplicate clause number")
+
+// ErrClauseNotFound 条款不存在或不属于指定标准
+var ErrClauseNotFound = errors.New("clause not found")
+
+// standardCodePattern 从标准类分类名中拆出标准号、版本和名称，如 "GM-T 0115-2021 信息系统密码应用测评要求"、"GBT 39786"、"GM-T 0034《...》"
+var standardCodePattern = regexp.MustCompile(`^(GM|GB)[-/]?([TZ])\s*(\d+(?:\.\d+)?)(?:-(\d{4}))?\s*(.*)$`)
+
+// ListStandards 获取标准目录，附带条款数和关联题目数
+func ListStandards() ([]models.StandardSummary, error) {
+	var summaries []models.StandardSummary
+	err := DB.Model(&models.Standard{}).
+		Select("standards.*, " +
+			"(SELECT count(*) FROM standard_clauses sc WHERE sc.standard_id = standards.id) AS clause_count, " +
+			"(SELECT count(DISTINCT qc.question_id) FROM question_clauses qc JOIN standard_clauses sc ON sc.id = qc.clause_id WHERE sc.standard_id = standards.id) AS question_count").
+		Order("code").
+		Find(&summaries).Error
+	return summaries, err
+}
+
+// GetStandard 获取标准及其条款，条款附带关联题目数，不含条款原文
+func GetStandard(id uint) (*models.Standard, error) {
+	var standard models.Standard
+	if err := DB.Preload("Clauses", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("content").Order("sort_order, id")
+	}).First(&standard, id).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		ClauseID uint
+		Count    int64
+	}
+	if err := DB.Model(&models.QuestionClause{}).
+		Select("question_clauses.clause_id, count(*) AS count").
+		Joins("JOIN standard_clauses sc ON sc.id = question_clauses.clause_id").
+		Where("sc.standard_id = ?", id).
+		Group("question_clauses.clause_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byClause := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byClause[c.ClauseID] = c.Count
+	}
+	for i := range standard.Clauses {
+		standard.Clauses[i].QuestionCount = byClause[standard.Clauses[i].ID]
+	}
+	return &standard, nil
+}
+
+// CreateStandard 创建标准及其条款
+func CreateStandard(req *models.StandardRequest) (*models.Standard, error) {
+	standard := models.Standard{
+		Code:     strings.TrimSpace(req.Code),
+		Title:    strings.TrimSpace(req.Title),
+		Version:  strings.TrimSpace(req.Version),
+		Category: strings.TrimSpace(req.Category),
+	}
+	clauses, err := normalizeClauses(req.Clauses)
+	if err != nil {
+		return nil, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Standard{}).Where("code = ?", standard.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrStandardExists
+		}
+		if err := tx.Create(&standard).Error; err != nil {
+			return err
+		}
+		_, err := mergeClauses(tx, standard.ID, clauses, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetStandard(standard.ID)
+}
+
+// UpdateStandard 更新标准，条款按编号合并：已有条款保留ID和题目关联，未提供原文时保留已有原文，未列出的条款连同关联一并删除
+func UpdateStandard(id uint, req *models.StandardRequest) (*models.Standard, error) {
+	clauses, err := normalizeClauses(req.Clauses)
+	if err != nil {
+		return nil, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var standard models.Standard
+		if err := tx.First(&standard, id).Error; err != nil {
+			return err
+		}
+
+		code := strings.TrimSpace(req.Code)
+		if code != standard.Code {
+			var count int64
+			if err := tx.Model(&models.Standard{}).Where("code = ? AND id <> ?", code, id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrStandardExists
+			}
+		}
+		standard.Code = code
+		standard.Title = strings.TrimSpace(req.Title)
+		standard.Version = strings.TrimSpace(req.Version)
+		standard.Category = strings.TrimSpace(req.Category)
+		if err := tx.Save(&standard).Error; err != nil {
+			return err
+		}
+
+		_, err := mergeClauses(tx, id, clauses, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetStandard(id)
+}
+
+// DeleteStandard 删除标准及其条款和题目关联
+func DeleteStandard(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var standard models.Standard
+		if err := tx.First(&standard, id).Error; err != nil {
+			return err
+		}
+		var clauseIDs []uint
+		if err := tx.Model(&models.StandardClause{}).Where("standard_id = ?", id).Pluck("id", &clauseIDs).Error; err != nil {
+			return err
+		}
+		if err := deleteClauses(tx, clauseIDs); err != nil {
+			return err
+		}
+		return tx.Delete(&standard).Error
+	})
+}
+
+// GetClauseQuestions 获取关联到指定条款的题目，按题目ID排序
+func GetClauseQuestions(standardID, clauseID uint) (*models.StandardClause, []models.Question, error) {
+	var clause models.StandardClause
+	err := DB.Where("id = ? AND standard_id = ?", clauseID, standardID).First(&clause).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrClauseNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var questions []models.Question
+	if err := DB.Where("id IN (?)", DB.Model(&models.QuestionClause{}).Select("question_id").Where("clause_id = ?", clauseID)).
+		Order("id").
+		Find(&questions).Error; err != nil {
+		return nil, nil, err
+	}
+	clause.QuestionCount = int64(len(questions))
+	return &clause, questions, nil
+}
+
+// GetQuestionClauses 获取题目关联的条款
+func GetQuestionClauses(questionID uint) ([]models.ClauseRef, error) {
+	refs := []models.ClauseRef{}
+	err := DB.Table("question_clauses qc").
+		Select("sc.id AS clause_id, s.id AS standard_id, s.code AS standard_code, sc.number, sc.title").
+		Joins("JOIN standard_clauses sc ON sc.id = qc.clause_id").
+		Joins("JOIN standards s ON s.id = sc.standard_id").
+		Where("qc.question_id = ?", questionID).
+		Order("s.code, sc.sort_order, sc.id").
+		Scan(&refs).Error
+	return refs, err
+}
+
+// SetQuestionClauses 替换题目关联的条款
+func SetQuestionClauses(questionID uint, clauseIDs []uint) ([]models.ClauseRef, error) {
+	if _, err := Cache.GetQuestion(questionID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(clauseIDs))
+	unique := make([]uint, 0, len(clauseIDs))
+	for _, id := range clauseIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if len(unique) > 0 {
+			var count int64
+			if err := tx.Model(&models.StandardClause{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(unique) {
+				return ErrClauseNotFound
+			}
+		}
+		if err := tx.Where("question_id = ?", questionID).Delete(&models.QuestionClause{}).Error; err != nil {
+			return err
+		}
+		if len(unique) == 0 {
+			return nil
+		}
+		links := make([]models.QuestionClause, len(unique))
+		for i, id := range unique {
+			links[i] = models.QuestionClause{QuestionID: questionID, ClauseID: id}
+		}
+		return tx.Create(&links).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetQuestionClauses(questionID)
+}
+
+// GetClauseStats 获取用户在指定标准各条款上的掌握情况，等待人工复核的答题不计入
+func GetClauseStats(userID, standardID uint) ([]models.ClauseStats, error) {
+	standard, err := GetStandard(standardID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每道题取最近一次作答判断是否已掌握
+	latest := DB.Model(&models.UserAnswer{}).
+		Select("max(id) AS id").
+		Where("user_id = ? AND review_status <> ?", userID, ReviewStatusPending).
+		Group("question_id")
+
+	var rows []struct {
+		ClauseID uint
+		Answered int
+		Total    int
+		Correct  int
+		Mastered int
+	}
+	if err := DB.Table("user_answers ua").
+		Select("qc.clause_id, count(DISTINCT ua.question_id) AS answered, count(*) AS total, "+
+			"sum(case when ua.is_correct then 1 else 0 end) AS correct, "+
+			"sum(case when latest.id IS NOT NULL AND ua.is_correct then 1 else 0 end) AS mastered").
+		Joins("JOIN question_clauses qc ON qc.question_id = ua.question_id").
+		Joins("JOIN standard_clauses sc ON sc.id = qc.clause_id").
+		Joins("LEFT JOIN (?) latest ON latest.id = ua.id", latest).
+		Where("ua.user_id = ? AND ua.review_status <> ? AND sc.standard_id = ?", userID, ReviewStatusPending, standardID).
+		Group("qc.clause_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byClause := make(map[uint]int, len(rows))
+	for i, row := range rows {
+		byClause[row.ClauseID] = i
+	}
+
+	stats := make([]models.ClauseStats, len(standard.Clauses))
+	for i, clause := range standard.Clauses {
+		stat := models.ClauseStats{
+			ClauseID:      clause.ID,
+			Number:        clause.Number,
+			Title:         clause.Title,
+			QuestionCount: int(clause.QuestionCount),
+		}
+		if idx, ok := byClause[clause.ID]; ok {
+			row := rows[idx]
+			stat.Answered = row.Answered
+			stat.Total = row.Total
+			stat.Correct = row.Correct
+			stat.Mastered = row.Mastered
+			if row.Total > 0 {
+				stat.Accuracy = float64(row.Correct) / float64(row.Total) * 100
+			}
+		}
+		if stat.QuestionCount > 0 {
+			stat.Mastery = float64(stat.Mastered) / float64(stat.QuestionCount) * 100
+		}
+		stats[i] = stat
+	}
+	return stats, nil
+}
+
+// normalizeClauses 整理请求中的条款，按请求顺序编排并检查编号重复
+func normalizeClauses(reqs []models.ClauseRequest) ([]models.StandardClause, error) {
+	clauses := make([]models.StandardClause, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i, req := range reqs {
+		number := strings.TrimSpace(req.Number)
+		if number == "" {
+			continue
+		}
+		if seen[number] {
+			return nil, ErrDuplicateClause
+		}
+		seen[number] = true
+		clauses = append(clauses, models.StandardClause{
+			Number:    number,
+			Title:     strings.TrimSpace(req.Title),
+			Content:   strings.TrimSpace(req.Content),
+			SortOrder: i + 1,
+		})
+	}
+	return clauses, nil
+}
+
+// mergeClauses 按编号合并条款：已有条款更新标题、顺序和原文（原文为空时保留），缺少的新增，
+// removeMissing为true时删除未列出的条款；需在事务中调用
+func mergeClauses(tx *gorm.DB, standardID uint, clauses []models.StandardClause, removeMissing bool) (*models.StandardTextImportResult, error) {
+	result := &models.StandardTextImportResult{Clauses: len(clauses)}
+
+	var existing []models.StandardClause
+	if err := tx.Where("standard_id = ?", standardID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byNumber := make(map[string]models.StandardClause, len(existing))
+	for _, c := range existing {
+		byNumber[c.Number] = c
+	}
+
+	for i := range clauses {
+		c := &clauses[i]
+		c.StandardID = standardID
+		if old, ok := byNumber[c.Number]; ok {
+			delete(byNumber, c.Number)
+			c.ID = old.ID
+			if c.Content == "" {
+				c.Content = old.Content
+			}
+			if err := tx.Model(&old).Updates(map[string]interface{}{
+				"title":      c.Title,
+				"content":    c.Content,
+				"sort_order": c.SortOrder,
+			}).Error; err != nil {
+				return nil, err
+			}
+			result.Updated++
+		} else {
+			if err := tx.Create(c).Error; err != nil {
+				return nil, err
+			}
+			result.Created++
+		}
+		if err := indexClause(tx, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if removeMissing {
+		removed := make([]uint, 0, len(byNumber))
+		for _, c := range byNumber {
+			removed = append(removed, c.ID)
+		}
+		if err := deleteClauses(tx, removed); err != nil {
+			return nil, err
+		}
+		result.Removed = len(removed)
+	}
+	return result, nil
+}
+
+// deleteClauses 删除条款及其题目关联和全文索引
+func deleteClauses(tx *gorm.DB, clauseIDs []uint) error {
+	if len(clauseIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("clause_id IN ?", clauseIDs).Delete(&models.QuestionClause{}).Error; err != nil {
+		return err
+	}
+	if err := unindexClauses(tx, clauseIDs); err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", clauseIDs).Delete(&models.StandardClause{}).Error
+}
+
+// seedStandardsFromCategories 标准目录为空时，按以标准号命名的题目分类生成标准条目（不含条款）
+func seedStandardsFromCategories() error {
+	var count int64
+	if err := DB.Model(&models.Standard{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var categories []string
+	if err := DB.Model(&models.Question{}).Distinct("category").Order("category").Pluck("category", &categories).Error; err != nil {
+		return err
+	}
+
+	var standards []models.Standard
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		standard, ok := standardFromCategory(category)
+		if !ok || seen[standard.Code] {
+			continue
+		}
+		seen[standard.Code] = true
+		standards = append(standards, standard)
+	}
+	if len(standards) == 0 {
+		return nil
+	}
+	if err := DB.Create(&standards).Error; err != nil {
+		return err
+	}
+	log.Printf("Seeded %d standards from question categories", len(standards))
+	return nil
+}
+
+// standardFromCategory 从分类名解析标准号、版本和名称
+func standardFromCategory(category string) (models.Standard, bool) {
+	m := standardCodePattern.FindStringSubmatch(strings.TrimSpace(category))
+	if m == nil {
+		return models.Standard{}, false
+	}
+	title := strings.TrimSpace(m[5])
+	title = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(title, "《"), "》"))
+	if title == "" {
+		title = category
+	}
+	return models.Standard{
+		Code:     m[1] + "/" + m[2] + " " + m[3],
+		Title:    title,
+		Version:  m[4],
+		Category: category,
+	}, true
+}
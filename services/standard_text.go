@@ -0,0 +1,339 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// MaxStandardTextSize 标准原文文件的最大字节数
+const MaxStandardTextSize = 2 << 20
+
+// clauseSearchTable 条款的FTS5全文索引表，rowid为条款ID
+const clauseSearchTable = "clause_fts"
+
+// clauseSearchWeights bm25中条款标题、原文的权重
+const clauseSearchWeights = "5.0, 1.0"
+
+// clauseExcerptLength 答题结果中条款原文摘录的最大字数
+const clauseExcerptLength = 300
+
+// maxClauseTitleLength 识别为条款标题的最大字数，更长的编号行视为正文
+const maxClauseTitleLength = 40
+
+var (
+	// ErrInvalidStandardText 标准原文不是UTF-8文本
+	ErrInvalidStandardText = errors.New("standard text must be UTF-8 encoded plain text or Markdown")
+	// ErrNoClausesFound 原文中没有识别出条款
+	ErrNoClausesFound = errors.New("no numbered clauses found in standard text")
+)
+
+var (
+	// clauseHeadingPattern 条款编号行，如 "5.2.1 密钥管理"、"A.1 概述"
+	clauseHeadingPattern = regexp.MustCompile(`^(\d{1,3}(?:\.\d{1,3}){0,5}|[A-Z](?:\.\d{1,3}){1,5})[\s\x{3000}]+(\S.*)$`)
+	// appendixHeadingPattern 附录标题，如 "附录A（资料性） 测评示例"
+	appendixHeadingPattern = regexp.MustCompile(`^附[\s\x{3000}]*录[\s\x{3000}]*([A-Z])(?:[\s\x{3000}]*[（(][^）)]*[）)])?[\s\x{3000}]*(.*)$`)
+	// tocPagePattern 目录行末尾的引导符和页码
+	tocPagePattern = regexp.MustCompile(`(?:[.·…]{2,}|[\s\x{3000}]{2,})[\s\x{3000}]*\d+$`)
+)
+
+// ImportStandardText 导入标准原文，按条款编号拆分后合并到标准的条款中
+// 已有条款更新标题和原文，保留题目关联；replace为true时删除原文中没有的条款
+func ImportStandardText(standardID uint, text string, replace bool) (*models.StandardTextImportResult, error) {
+	clauses, err := parseStandardText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.StandardTextImportResult
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var standard models.Standard
+		if err := tx.First(&standard, standardID).Error; err != nil {
+			return err
+		}
+		result, err = mergeClauses(tx, standardID, clauses, replace)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseStandardText 将Markdown或纯文本的标准原文拆分为条款
+// 以 "5.2.1 标题"、"A.1 标题"、"附录A" 开头的行及Markdown标题作为条款起点，其后的文本为条款原文；
+// 第一个条款之前的封面等内容忽略。目录与正文中重复出现的编号合并为一个条款
+func parseStandardText(text string) ([]models.StandardClause, error) {
+	if !utf8.ValidString(text) {
+		return nil, ErrInvalidStandardText
+	}
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var clauses []models.StandardClause
+	var body []string
+	index := make(map[string]int)
+	current := -1
+
+	flush := func() {
+		if current < 0 {
+			return
+		}
+		content := joinClauseLines(body)
+		body = body[:0]
+		if content == "" {
+			return
+		}
+		if clauses[current].Content != "" {
+			content = clauses[current].Content + "\n\n" + content
+		}
+		clauses[current].Content = content
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		number, title, ok := parseClauseHeading(line)
+		if !ok {
+			if current >= 0 {
+				body = append(body, strings.TrimRight(line, " \t　"))
+			}
+			continue
+		}
+
+		flush()
+		if i, seen := index[number]; seen {
+			// 目录中的条目只有标题，正文中再次出现时沿用首次的位置
+			if title != "" {
+				clauses[i].Title = title
+			}
+			current = i
+			continue
+		}
+		index[number] = len(clauses)
+		current = len(clauses)
+		clauses = append(clauses, models.StandardClause{
+			Number:    number,
+			Title:     title,
+			SortOrder: len(clauses) + 1,
+		})
+	}
+	flush()
+
+	if len(clauses) == 0 {
+		return nil, ErrNoClausesFound
+	}
+	return clauses, nil
+}
+
+// parseClauseHeading 判断一行是否为条款标题，返回条款编号和标题
+func parseClauseHeading(line string) (string, string, bool) {
+	line = strings.Trim(line, " \t　")
+	markdown := strings.HasPrefix(line, "#")
+	if markdown {
+		line = strings.Trim(strings.TrimLeft(line, "#"), " \t　")
+	}
+	line = strings.Trim(strings.TrimSuffix(strings.TrimPrefix(line, "**"), "**"), " \t　")
+	line = tocPagePattern.ReplaceAllString(line, "")
+	if line == "" {
+		return "", "", false
+	}
+
+	if m := appendixHeadingPattern.FindStringSubmatch(line); m != nil {
+		return "附录" + m[1], strings.TrimSpace(m[2]), true
+	}
+	if m := clauseHeadingPattern.FindStringSubmatch(line); m != nil {
+		title := strings.TrimSpace(m[2])
+		if utf8.RuneCountInString(title) <= maxClauseTitleLength && !strings.ContainsAny(lastRune(title), "。；;，,：:、") {
+			return m[1], title, true
+		}
+	}
+	// 没有编号的Markdown标题（如 "## 前言"）以标题文字作为编号
+	if markdown && utf8.RuneCountInString(line) <= maxClauseTitleLength {
+		return line, "", true
+	}
+	return "", "", false
+}
+
+// lastRune 返回字符串的最后一个字符
+func lastRune(s string) string {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	if r == utf8.RuneError {
+		return ""
+	}
+	return string(r)
+}
+
+// joinClauseLines 拼接条款正文，去掉首尾空行并将连续空行合并为一行
+func joinClauseLines(lines []string) string {
+	var sb strings.Builder
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank = sb.Len() > 0
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+			if blank {
+				sb.WriteString("\n")
+			}
+		}
+		sb.WriteString(line)
+		blank = false
+	}
+	return sb.String()
+}
+
+// initClauseSearchIndex 创建条款的FTS5全文索引，索引条款数不一致时重建；FTS5不可用时条款搜索退回LIKE查询
+func initClauseSearchIndex() error {
+	if !searchIndexReady {
+		return nil
+	}
+	if err := DB.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + clauseSearchTable +
+		" USING fts5(title, content, tokenize = 'unicode61 remove_diacritics 2')").Error; err != nil {
+		return err
+	}
+
+	var indexed, total int64
+	if err := DB.Table(clauseSearchTable).Count(&indexed).Error; err != nil {
+		return err
+	}
+	if err := DB.Model(&models.StandardClause{}).Count(&total).Error; err != nil {
+		return err
+	}
+	if indexed == total {
+		return nil
+	}
+
+	log.Printf("Rebuilding clause search index for %d clauses...", total)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + clauseSearchTable).Error; err != nil {
+			return err
+		}
+		var clauses []models.StandardClause
+		return tx.Model(&models.StandardClause{}).FindInBatches(&clauses, 500, func(batch *gorm.DB, _ int) error {
+			for i := range clauses {
+				if err := insertClauseIndex(tx, &clauses[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// indexClause 更新条款的全文索引，需在写入条款的事务中调用
+func indexClause(tx *gorm.DB, clause *models.StandardClause) error {
+	if !searchIndexReady {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM "+clauseSearchTable+" WHERE rowid = ?", clause.ID).Error; err != nil {
+		return err
+	}
+	return insertClauseIndex(tx, clause)
+}
+
+// insertClauseIndex 写入条款的分词文本
+func insertClauseIndex(tx *gorm.DB, clause *models.StandardClause) error {
+	return tx.Exec("INSERT INTO "+clauseSearchTable+" (rowid, title, content) VALUES (?, ?, ?)",
+		clause.ID, searchIndexText(clause.Number+" "+clause.Title), searchIndexText(clause.Content)).Error
+}
+
+// unindexClauses 删除条款的全文索引
+func unindexClauses(tx *gorm.DB, clauseIDs []uint) error {
+	if !searchIndexReady || len(clauseIDs) == 0 {
+		return nil
+	}
+	return tx.Exec("DELETE FROM "+clauseSearchTable+" WHERE rowid IN ?", clauseIDs).Error
+}
+
+// clauseRow 条款查询结果，附带原文用于生成摘录和高亮片段
+type clauseRow struct {
+	models.ClauseRef
+	QuestionID uint
+	Rank       float64
+	Content    string
+}
+
+// clauseRefColumns 查询ClauseRef所需的列，需关联 standard_clauses sc 和 standards s
+const clauseRefColumns = "sc.id AS clause_id, s.id AS standard_id, s.code AS standard_code, sc.number, sc.title, sc.content"
+
+// SearchClauses 全文搜索条款标题和原文，语法同题目搜索；standardID为0时搜索全部标准
+func SearchClauses(keyword string, standardID uint, limit int) ([]models.ClauseSearchHit, error) {
+	parsed, err := parseSearchQuery(keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	query := DB.Table("standard_clauses sc").
+		Joins("JOIN standards s ON s.id = sc.standard_id")
+	if standardID > 0 {
+		query = query.Where("sc.standard_id = ?", standardID)
+	}
+	if searchIndexReady {
+		hits := DB.Table(clauseSearchTable).
+			Select("rowid, bm25("+clauseSearchTable+", "+clauseSearchWeights+") AS rank").
+			Where(clauseSearchTable+" MATCH ?", parsed.ftsMatch())
+		query = query.Joins("JOIN (?) AS hits ON hits.rowid = sc.id", hits).
+			Select(clauseRefColumns + ", hits.rank").
+			Order("hits.rank, sc.id")
+	} else {
+		query = parsed.likeColumns(query, "sc.number", "sc.title", "sc.content").
+			Select(clauseRefColumns).
+			Order("s.code, sc.sort_order, sc.id")
+	}
+
+	var rows []clauseRow
+	if err := query.Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	terms := parsed.terms()
+	hits := make([]models.ClauseSearchHit, len(rows))
+	for i, row := range rows {
+		hits[i] = models.ClauseSearchHit{ClauseRef: row.ClauseRef, Rank: row.Rank}
+		hits[i].Snippet = highlightSnippet(row.Content, terms)
+		if hits[i].Snippet == "" {
+			hits[i].Snippet = highlightSnippet(row.Title, terms)
+		}
+	}
+	return hits, nil
+}
+
+// GetClauseExcerpts 获取题目关联的条款及原文摘录，按题目ID分组
+func GetClauseExcerpts(questionIDs []uint) (map[uint][]models.ClauseRef, error) {
+	result := make(map[uint][]models.ClauseRef)
+	if len(questionIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []clauseRow
+	if err := DB.Table("question_clauses qc").
+		Select("qc.question_id, "+clauseRefColumns).
+		Joins("JOIN standard_clauses sc ON sc.id = qc.clause_id").
+		Joins("JOIN standards s ON s.id = sc.standard_id").
+		Where("qc.question_id IN ?", questionIDs).
+		Order("s.code, sc.sort_order, sc.id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		ref := row.ClauseRef
+		ref.Excerpt = clauseExcerpt(row.Content)
+		result[row.QuestionID] = append(result[row.QuestionID], ref)
+	}
+	return result, nil
+}
+
+// clauseExcerpt 截取条款原文的开头部分
+func clauseExcerpt(content string) string {
+	runes := []rune(content)
+	if len(runes) <= clauseExcerptLength {
+		return content
+	}
+	return strings.TrimSpace(string(runes[:clauseExcerptLength])) + "…"
+}
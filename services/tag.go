@@ -0,0 +1,99 @@
+package services
+
+import (
+	"strings"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// ParseTags 解析以逗号、分号或竖线分隔的标签列表
+func ParseTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		switch r {
+		case ',', '，', ';', '；', '|':
+			return true
+		}
+		return false
+	})
+	return NormalizeTags(fields)
+}
+
+// NormalizeTags 去除空白和重复的标签，保持原有顺序
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// tagsEqual 比较两组已规范化的标签
+func tagsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveQuestionTags 重建题目在question_tags中的标签索引
+func saveQuestionTags(tx *gorm.DB, questionID uint, tags []string) error {
+	if err := tx.Where("question_id = ?", questionID).Delete(&models.QuestionTag{}).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	rows := make([]models.QuestionTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = models.QuestionTag{QuestionID: questionID, Tag: tag}
+	}
+	return tx.Create(&rows).Error
+}
+
+// TagScope 筛选同时包含所有指定标签的题目，column为题目ID列
+func TagScope(column string, tags []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(tags) == 0 {
+			return db
+		}
+
+		subQuery := DB.Model(&models.QuestionTag{}).
+			Select("question_id").
+			Where("tag IN ?", tags).
+			Group("question_id").
+			Having("COUNT(DISTINCT tag) = ?", len(tags))
+		return db.Where(column+" IN (?)", subQuery)
+	}
+}
+
+// GetTags 获取所有标签及题目数，可按分类（含分类树子节点）筛选
+func GetTags(category string) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := DB.Table("question_tags qt").
+		Select("qt.tag as name, count(*) as count").
+		Joins("JOIN questions q ON q.id = qt.question_id").
+		Scopes(CategoryScope("q.category", category)).
+		Group("qt.tag").
+		Order("count DESC, name").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
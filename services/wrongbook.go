@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"quiz-system/models"
+	"gorm.io/gorm"
+)
+
+// WrongBookGraduationStreak 连续答对该次数后题目自动移出错题本
+const WrongBookGraduationStreak = 3
+
+// 错题本条目状态
+const (
+	WrongStatusActive    = "active"
+	WrongStatusGraduated = "graduated" // 连续答对后自动移出
+	WrongStatusRemoved   = "removed"   // 用户手动移出
+)
+
+// ErrInvalidWrongStatus 错题本状态筛选条件无效
+var ErrInvalidWrongStatus = errors.New("status must be one of active, graduated, removed, all")
+
+// updateWrongBook 按答题记录更新错题本，需在保存答题记录的事务中调用
+// 答错时加入或重新加入错题本；答对时累计连续答对次数，达到WrongBookGraduationStreak后移出
+func updateWrongBook(tx *gorm.DB, answer *models.UserAnswer) error {
+	if answer.ReviewStatus == ReviewStatusPending {
+		return nil
+	}
+
+	var entry models.WrongBookEntry
+	err := tx.Where("user_id = ? AND question_id = ?", answer.UserID, answer.QuestionID).First(&entry).Error
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !isNew {
+		return err
+	}
+	if isNew && answer.IsCorrect {
+		return nil
+	}
+	if isNew {
+		entry = models.WrongBookEntry{UserID: answer.UserID, QuestionID: answer.QuestionID}
+	}
+
+	now := answer.AnsweredAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if !applyWrongBookAnswer(&entry, answer, now) {
+		return nil
+	}
+	return tx.Save(&entry).Error
+}
+
+// applyWrongBookAnswer 按一次作答更新错题本条目，条目无需更新时返回false
+func applyWrongBookAnswer(entry *models.WrongBookEntry, answer *models.UserAnswer, now time.Time) bool {
+	if answer.IsCorrect {
+		if entry.Status != WrongStatusActive {
+			return false
+		}
+		entry.CorrectStreak++
+		if entry.CorrectStreak >= WrongBookGraduationStreak {
+			entry.Status = WrongStatusGraduated
+			entry.ResolvedAt = &now
+		}
+		return true
+	}
+
+	if entry.WrongCount == 0 {
+		entry.FirstWrongAt = now
+	}
+	entry.WrongCount++
+	entry.CorrectStreak = 0
+	entry.Status = WrongStatusActive
+	entry.Category = answer.Category
+	entry.LastAnswer = answer.UserAnswer
+	entry.LastWrongAt = now
+	entry.ResolvedAt = nil
+	return true
+}
+
+// wrongBookQuery 错题本查询，status为all时不限状态，category可为分类树父节点
+func wrongBookQuery(userID uint, category, status string) *gorm.DB {
+	query := DB.Table("wrong_book_entries w").
+		Joins("JOIN questions q ON q.id = w.question_id").
+		Scopes(CategoryScope("w.category", category)).
+		Where("w.user_id = ?", userID)
+	if status != "all" {
+		query = query.Where("w.status = ?", status)
+	}
+	return query
+}
+
+// GetWrongBook 获取错题本，最近答错的在前；status为空时返回错题本中现有的题目
+func GetWrongBook(userID uint, category, status string, limit, offset int) ([]models.WrongQuestion, int64, error) {
+	switch status {
+	case "":
+		status = WrongStatusActive
+	case WrongStatusActive, WrongStatusGraduated, WrongStatusRemoved, "all":
+	default:
+		return nil, 0, ErrInvalidWrongStatus
+	}
+
+	var total int64
+	if err := wrongBookQuery(userID, category, status).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.WrongQuestion
+	if err := wrongBookQuery(userID, category, status).
+		Select("w.question_id, q.question, w.last_answer AS user_answer, q.answer AS correct_answer, w.category, " +
+			"w.status, w.wrong_count, w.correct_streak, w.first_wrong_at, w.last_wrong_at, w.resolved_at").
+		Order("w.last_wrong_at DESC, w.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// RemoveWrongQuestion 手动将题目移出错题本，再次答错时重新加入
+func RemoveWrongQuestion(userID, questionID uint) error {
+	now := time.Now()
+	result := DB.Model(&models.WrongBookEntry{}).
+		Where("user_id = ? AND question_id = ? AND status = ?", userID, questionID, WrongStatusActive).
+		Updates(map[string]interface{}{
+			"status":      WrongStatusRemoved,
+			"resolved_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// backfillWrongBook 错题本为空时，按时间顺序回放已有答题记录生成错题本
+func backfillWrongBook() error {
+	var entryCount, wrongCount int64
+	if err := DB.Model(&models.WrongBookEntry{}).Count(&entryCount).Error; err != nil {
+		return err
+	}
+	if entryCount > 0 {
+		return nil
+	}
+	if err := DB.Model(&models.UserAnswer{}).
+		Where("is_correct = ? AND review_status <> ?", false, ReviewStatusPending).
+		Count(&wrongCount).Error; err != nil {
+		return err
+	}
+	if wrongCount == 0 {
+		return nil
+	}
+
+	type entryKey struct{ userID, questionID uint }
+	entries := make(map[entryKey]*models.WrongBookEntry)
+	err := replayGradedAnswers(func(answer *models.UserAnswer) {
+		key := entryKey{answer.UserID, answer.QuestionID}
+		entry, ok := entries[key]
+		if !ok {
+			if answer.IsCorrect {
+				return
+			}
+			entry = &models.WrongBookEntry{UserID: answer.UserID, QuestionID: answer.QuestionID}
+			entries[key] = entry
+		}
+		applyWrongBookAnswer(entry, answer, answer.AnsweredAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*models.WrongBookEntry, 0, len(entries))
+	for _, entry := range entries {
+		batch = append(batch, entry)
+	}
+	if err := DB.CreateInBatches(batch, 500).Error; err != nil {
+		return err
+	}
+	log.Printf("Built wrong-question book for %d questions from %d wrong answers", len(batch), wrongCount)
+	return nil
+}
+
+// 错题组卷参数
+const (
+	WrongExamType         = "wrong_questions"
+	defaultWrongExamCount = 50
+	wrongRecencyHalfLife  = 14.0 // 天，距最近答错每过该天数权重减半
+	resolvedWrongWeight   = 0.5  // 已移出错题本题目的权重系数
+)
+
+// ErrNoWrongQuestions 没有符合条件的错题
+var ErrNoWrongQuestions = errors.New("no wrong questions match the filter")
+
+// ValidateWrongExamOptions 校验错题组卷参数并填充默认值
+func ValidateWrongExamOptions(options *models.WrongExamOptions) error {
+	if options.Count < 0 {
+		return errors.New("count cannot be negative")
+	}
+	if options.Count == 0 {
+		options.Count = defaultWrongExamCount
+	}
+	if options.Count > maxBlueprintQuestions {
+		return fmt.Errorf("count cannot be more than %d", maxBlueprintQuestions)
+	}
+	for _, t := range options.Types {
+		if questionTypeNames[t] == "" {
+			return fmt.Errorf("unknown question type %q", t)
+		}
+	}
+	if options.Duration == nil {
+		duration := options.Count
+		options.Duration = &duration
+	}
+	if *options.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+	return nil
+}
+
+// wrongCandidate 错题组卷的候选题目
+type wrongCandidate struct {
+	QuestionID  uint
+	Status      string
+	WrongCount  int
+	LastWrongAt time.Time
+}
+
+// weight 抽题权重：答错次数乘以按最近答错时间衰减的系数，已移出错题本的题目降低权重
+func (w wrongCandidate) weight(now time.Time) float64 {
+	days := now.Sub(w.LastWrongAt).Hours() / 24
+	if days < 0 {
+		days = 0
+	}
+	weight := float64(w.WrongCount) * math.Pow(0.5, days/wrongRecencyHalfLife)
+	if w.Status != WrongStatusActive {
+		weight *= resolvedWrongWeight
+	}
+	return weight
+}
+
+// SelectWrongExamQuestions 从用户的错题中按权重不放回随机抽题
+// 默认只抽取错题本中的题目，IncludeResolved为true时包含已连续答对移出的题目；手动移出的题目不抽取
+// filter中的分类、标签、难度进一步限定抽题范围
+func SelectWrongExamQuestions(userID uint, options *models.WrongExamOptions, filter QuestionFilter) ([]models.Question, error) {
+	statuses := []string{WrongStatusActive}
+	if options.IncludeResolved {
+		statuses = append(statuses, WrongStatusGraduated)
+	}
+
+	filter.Type = ""
+	scope := DB.Model(&models.Question{}).Select("id").Scopes(filter.Scope)
+	if len(options.Types) > 0 {
+		scope = scope.Where("type IN ?", options.Types)
+	}
+
+	var candidates []wrongCandidate
+	if err := DB.Model(&models.WrongBookEntry{}).
+		Select("question_id, status, wrong_count, last_wrong_at").
+		Where("user_id = ? AND status IN ?", userID, statuses).
+		Where("question_id IN (?)", scope).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoWrongQuestions
+	}
+
+	// 加权不放回抽样：每题取 ln(u)/w 作为键，取键最大的Count道
+	now := time.Now()
+	keys := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		keys[i] = math.Log(1-rand.Float64()) / math.Max(candidate.weight(now), 1e-9)
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return keys[order[a]] > keys[order[b]] })
+
+	questions := make([]models.Question, 0, options.Count)
+	for _, i := range order {
+		if len(questions) == options.Count {
+			break
+		}
+		question, err := Cache.GetQuestion(candidates[i].QuestionID)
+		if err != nil {
+			continue
+		}
+		questions = append(questions, *question)
+	}
+	if len(questions) == 0 {
+		return nil, ErrNoWrongQuestions
+	}
+	return questions, nil
+}
@@ -5,7 +5,12 @@ const AppState = {
     currentQuestions: [],
     currentQuestionIndex: 0,
     examTimer: null,
-    isExamMode: false
+    isExamMode: false,
+    isReviewMode: false,
+    sequentialCategory: null,
+    wrongBookCategory: '',
+    wrongBookOffset: 0,
+    planQuestions: []
 };
 
 // API 基础URL
@@ -19,6 +24,14 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+// 转义文本并将附件引用渲染为图片 ![说明](/api/attachments/<sha256>) 或链接 [文件名](/api/attachments/<sha256>)
+function renderRichText(text) {
+    return escapeHtml(text).replace(/(!?)\[([^\]"<>]*)\]\((\/api\/attachments\/[0-9a-f]{64})\)/g, (match, image, label, url) =>
+        image ?
+            `<img src="${url}" alt="${label}" loading="lazy" class="max-w-full my-2 rounded border">` :
+            `<a href="${url}" target="_blank" class="text-primary underline">${label || '附件'}</a>`);
+}
+
 // 页面初始化
 document.addEventListener('DOMContentLoaded', function() {
     checkAuthStatus();
@@ -229,11 +242,11 @@ async function showCategories() {
     }
 }
 
-// 开始分类练习
-async function startCategoryPractice(category) {
+// 开始分类练习（按顺序，从上次练习的位置继续）
+async function startCategoryPractice(category, restart = false) {
     try {
         showLoading(true);
-        const response = await fetch(`${API_BASE}/questions?category=${encodeURIComponent(category)}&limit=20`, {
+        const response = await fetch(`${API_BASE}/practice/sequential?category=${encodeURIComponent(category)}&limit=20${restart ? '&restart=true' : ''}`, {
             credentials: 'include'
         });
         
@@ -242,9 +255,21 @@ async function startCategoryPractice(category) {
         }
         
         const data = await response.json();
+        if (data.questions.length === 0 && data.progress.finished) {
+            if (confirm(`「${category}」已全部练习完（共${data.progress.total}题），是否从头开始？`)) {
+                showLoading(false);
+                return startCategoryPractice(category, true);
+            }
+            return;
+        }
+        if (data.progress.completed > 0 && !restart) {
+            showMessage(`从上次位置继续：已完成 ${data.progress.completed}/${data.progress.total} 题`, 'info');
+        }
         AppState.currentQuestions = data.questions;
         AppState.currentQuestionIndex = 0;
         AppState.isExamMode = false;
+        AppState.sequentialCategory = category;
+        AppState.isReviewMode = false;
         
         showQuestionPage();
     } catch (error) {
@@ -254,11 +279,188 @@ async function startCategoryPractice(category) {
     }
 }
 
+// 间隔复习：按复习计划获取到期的题目
+async function showDueReviews() {
+    try {
+        showLoading(true);
+        const response = await fetch(`${API_BASE}/review/due?limit=20`, {
+            credentials: 'include'
+        });
+        
+        if (!response.ok) {
+            throw new Error('Failed to fetch due reviews');
+        }
+        
+        const data = await response.json();
+        if (data.questions.length === 0) {
+            showMessage(`暂无到期的复习题目（复习计划中共 ${data.summary.total} 题，今天还有 ${data.summary.due_today} 题到期）`, 'info');
+            return;
+        }
+        AppState.currentQuestions = data.questions;
+        AppState.currentQuestionIndex = 0;
+        AppState.isExamMode = false;
+        AppState.isReviewMode = true;
+        AppState.sequentialCategory = null;
+        
+        showQuestionPage();
+    } catch (error) {
+        showMessage('加载复习题目失败', 'error');
+    } finally {
+        showLoading(false);
+    }
+}
+
+// 今日学习计划：到期复习、薄弱分类强化和新题，显示完成情况和每日设置
+async function showStudyPlan(rebuild = false) {
+    try {
+        showLoading(true);
+        const [planResponse, settingsResponse, historyResponse] = await Promise.all([
+            fetch(`${API_BASE}/plan/today${rebuild ? '?rebuild=true' : ''}`, { credentials: 'include' }),
+            fetch(`${API_BASE}/plan/settings`, { credentials: 'include' }),
+            fetch(`${API_BASE}/plan/history?days=14`, { credentials: 'include' })
+        ]);
+        
+        if (!planResponse.ok || !settingsResponse.ok || !historyResponse.ok) {
+            throw new Error('Failed to fetch study plan');
+        }
+        
+        const plan = await planResponse.json();
+        const settings = await settingsResponse.json();
+        const history = await historyResponse.json();
+        AppState.planQuestions = plan.questions;
+        
+        const sectionNames = { review: '到期复习', weak: '薄弱分类强化', new: '新题' };
+        const examDate = settings.exam_date ? settings.exam_date.slice(0, 10) : '';
+        
+        const contentArea = document.getElementById('content-area');
+        contentArea.innerHTML = `
+            <div class="fade-in">
+                <div class="bg-white rounded-lg shadow-md p-6 mb-6">
+                    <div class="flex justify-between items-center mb-4">
+                        <h2 class="text-2xl font-bold text-gray-900">今日计划（${plan.date}）</h2>
+                        ${plan.days_until_exam !== undefined && plan.days_until_exam !== null ?
+                            `<span class="text-sm text-red-600 font-bold">距离考试还有 ${plan.days_until_exam} 天</span>` : ''}
+                    </div>
+                    <div class="mb-4">
+                        <div class="flex justify-between text-sm text-gray-600 mb-1">
+                            <span>已完成 ${plan.completed} / ${plan.total} 题</span>
+                            <span>${plan.completion.toFixed(0)}%</span>
+                        </div>
+                        <div class="w-full bg-gray-200 rounded-full h-2">
+                            <div class="bg-success h-2 rounded-full" style="width: ${plan.completion}%"></div>
+                        </div>
+                    </div>
+                    <div class="grid grid-cols-1 md:grid-cols-3 gap-4 mb-4">
+                        ${plan.sections.map(section => `
+                            <div class="border border-gray-200 rounded-lg p-4 text-center">
+                                <div class="text-sm text-gray-600">${sectionNames[section.kind]}</div>
+                                <div class="text-xl font-bold text-gray-900">${section.completed} / ${section.total}</div>
+                            </div>
+                        `).join('')}
+                    </div>
+                    <div class="flex space-x-4">
+                        ${plan.completed < plan.total ? `
+                            <button onclick="practiceStudyPlan()" class="bg-primary text-white px-6 py-2 rounded-lg hover:bg-blue-600">
+                                继续练习
+                            </button>
+                        ` : '<span class="text-success font-medium">今日计划已完成</span>'}
+                        <button onclick="showStudyPlan(true)" class="bg-secondary text-white px-6 py-2 rounded-lg hover:bg-gray-600">
+                            重新安排未完成题目
+                        </button>
+                    </div>
+                </div>
+                
+                <div class="bg-white rounded-lg shadow-md p-6 mb-6">
+                    <h3 class="text-lg font-semibold text-gray-900 mb-4">每日设置</h3>
+                    <form id="study-settings-form" class="grid grid-cols-1 md:grid-cols-3 gap-4" onsubmit="event.preventDefault(); saveStudySettings();">
+                        <label class="block text-sm font-medium text-gray-700">每日题量（0为不限）
+                            <input type="number" name="daily_questions" min="0" max="300" value="${settings.daily_questions}" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                        </label>
+                        <label class="block text-sm font-medium text-gray-700">每日学习时间（分钟，0为不限）
+                            <input type="number" name="daily_minutes" min="0" max="600" value="${settings.daily_minutes}" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                        </label>
+                        <label class="block text-sm font-medium text-gray-700">考试日期
+                            <input type="date" name="exam_date" value="${examDate}" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                        </label>
+                        <div class="md:col-span-3">
+                            <button type="submit" class="bg-primary text-white px-6 py-2 rounded-lg hover:bg-blue-600">保存设置</button>
+                        </div>
+                    </form>
+                </div>
+                
+                <div class="bg-white rounded-lg shadow-md p-6">
+                    <h3 class="text-lg font-semibold text-gray-900 mb-4">最近完成情况（连续完成 ${history.streak} 天）</h3>
+                    ${history.history.length === 0 ? '<p class="text-gray-600">暂无记录</p>' : `
+                        <div class="space-y-2">
+                            ${history.history.map(day => `
+                                <div class="flex items-center text-sm">
+                                    <span class="w-28 text-gray-600">${day.date}</span>
+                                    <div class="flex-1 bg-gray-200 rounded-full h-2 mx-3">
+                                        <div class="bg-success h-2 rounded-full" style="width: ${day.completion}%"></div>
+                                    </div>
+                                    <span class="w-20 text-right text-gray-600">${day.completed} / ${day.total}</span>
+                                </div>
+                            `).join('')}
+                        </div>
+                    `}
+                </div>
+                
+                <div class="mt-6 text-center">
+                    <button onclick="showWelcomeContent()" class="text-gray-600 hover:text-gray-800">
+                        ← 返回首页
+                    </button>
+                </div>
+            </div>
+        `;
+    } catch (error) {
+        showMessage('加载学习计划失败', 'error');
+    } finally {
+        showLoading(false);
+    }
+}
+
+// 练习今日计划中未完成的题目
+function practiceStudyPlan() {
+    AppState.currentQuestions = AppState.planQuestions.filter(question => !question.done);
+    AppState.currentQuestionIndex = 0;
+    AppState.isExamMode = false;
+    AppState.isReviewMode = false;
+    AppState.sequentialCategory = null;
+    
+    showQuestionPage();
+}
+
+// 保存每日学习设置，保存后按新设置重新安排未完成的题目
+async function saveStudySettings() {
+    const form = document.getElementById('study-settings-form');
+    try {
+        const response = await fetch(`${API_BASE}/plan/settings`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'include',
+            body: JSON.stringify({
+                daily_questions: parseInt(form.daily_questions.value) || 0,
+                daily_minutes: parseInt(form.daily_minutes.value) || 0,
+                exam_date: form.exam_date.value
+            })
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            showMessage(data.error, 'error');
+            return;
+        }
+        showMessage('设置已保存', 'success');
+        showStudyPlan(true);
+    } catch (error) {
+        showMessage('保存设置失败', 'error');
+    }
+}
+
 // 显示随机练习
 async function showPractice() {
     try {
         showLoading(true);
-        const response = await fetch(`${API_BASE}/questions?limit=20`, {
+        const response = await fetch(`${API_BASE}/questions?limit=20&unseen_first=true`, {
             credentials: 'include'
         });
         
@@ -270,6 +472,8 @@ async function showPractice() {
         AppState.currentQuestions = data.questions;
         AppState.currentQuestionIndex = 0;
         AppState.isExamMode = false;
+        AppState.sequentialCategory = null;
+        AppState.isReviewMode = false;
         
         showQuestionPage();
     } catch (error) {
@@ -281,9 +485,11 @@ async function showPractice() {
 
 // 开始考试
 async function startExam(examType) {
-    if (!confirm(examType === 'mock_exam' ? 
-        '即将开始模拟考试（180题，180分钟），确定开始吗？' : 
-        '即将开始练习模式，确定开始吗？')) {
+    const confirmMessages = {
+        mock_exam: '即将开始模拟考试（180题，180分钟），确定开始吗？',
+        wrong_questions: '即将从错题中抽题组卷（最多50题，每题1分钟），确定开始吗？'
+    };
+    if (!confirm(confirmMessages[examType] || '即将开始练习模式，确定开始吗？')) {
         return;
     }
     
@@ -295,22 +501,189 @@ async function startExam(examType) {
         });
         
         if (!response.ok) {
+            if (examType === 'wrong_questions' && response.status === 400) {
+                showMessage('错题本中没有可组卷的题目', 'info');
+                return;
+            }
             throw new Error('Failed to start exam');
         }
         
-        const data = await response.json();
-        AppState.currentExamSession = data.session_id;
-        AppState.currentQuestions = data.questions;
-        AppState.currentQuestionIndex = 0;
-        AppState.isExamMode = true;
+        beginExamSession(await response.json());
+    } catch (error) {
+        showMessage('开始考试失败', 'error');
+    } finally {
+        showLoading(false);
+    }
+}
+
+// 进入考试会话（模拟考试、错题组卷、自定义练习共用）
+function beginExamSession(data) {
+    AppState.currentExamSession = data.session_id;
+    AppState.currentQuestions = data.questions;
+    AppState.currentQuestionIndex = 0;
+    AppState.isExamMode = true;
+    AppState.sequentialCategory = null;
+    AppState.isReviewMode = false;
+    
+    if (data.duration > 0) {
+        startExamTimer(data.duration);
+    }
+    
+    showQuestionPage();
+}
+
+// 显示自定义练习设置
+async function showCustomPractice() {
+    try {
+        showLoading(true);
+        const response = await fetch(`${API_BASE}/questions/categories`, {
+            credentials: 'include'
+        });
         
-        if (data.duration > 0) {
-            startExamTimer(data.duration);
+        if (!response.ok) {
+            throw new Error('Failed to fetch categories');
         }
         
-        showQuestionPage();
+        const data = await response.json();
+        const typeNames = {
+            single: '单选题', multiple: '多选题', judge: '判断题', fill: '填空题',
+            short: '简答题', ordering: '排序题', matching: '匹配题'
+        };
+        
+        const contentArea = document.getElementById('content-area');
+        contentArea.innerHTML = `
+            <div class="fade-in">
+                <div class="bg-white rounded-lg shadow-md p-6">
+                    <h2 class="text-2xl font-bold text-gray-900 mb-6">自定义练习</h2>
+                    <form id="custom-practice-form" class="space-y-4" onsubmit="event.preventDefault(); startCustomPractice();">
+                        <div>
+                            <label class="block text-sm font-medium text-gray-700 mb-1">分类（可多选，不选为全部）</label>
+                            <select name="categories" multiple size="6" class="w-full border border-gray-300 rounded-md p-2">
+                                ${data.categories.map(category => `
+                                    <option value="${escapeHtml(category.name).replace(/"/g, '&quot;')}">${escapeHtml(category.name)} (${category.count})</option>
+                                `).join('')}
+                            </select>
+                        </div>
+                        <div>
+                            <span class="block text-sm font-medium text-gray-700 mb-1">题型（不选为全部）</span>
+                            ${Object.entries(typeNames).map(([type, name]) => `
+                                <label class="inline-flex items-center mr-4">
+                                    <input type="checkbox" name="types" value="${type}" class="mr-1">${name}
+                                </label>
+                            `).join('')}
+                        </div>
+                        <div class="grid grid-cols-1 md:grid-cols-2 gap-4">
+                            <label class="block text-sm font-medium text-gray-700">标签（逗号分隔，需同时包含）
+                                <input type="text" name="tags" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                            </label>
+                            <label class="block text-sm font-medium text-gray-700">难度（如 3 或 2-4）
+                                <input type="text" name="difficulty" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                            </label>
+                        </div>
+                        <div>
+                            <label class="inline-flex items-center mr-4"><input type="checkbox" name="only_unseen" class="mr-1">只选未做过的</label>
+                            <label class="inline-flex items-center mr-4"><input type="checkbox" name="only_wrong" class="mr-1">只选错题</label>
+                            <label class="inline-flex items-center mr-4"><input type="checkbox" name="only_bookmarked" class="mr-1">只选收藏</label>
+                        </div>
+                        <div class="grid grid-cols-1 md:grid-cols-3 gap-4">
+                            <label class="block text-sm font-medium text-gray-700">排除最近几天做过的
+                                <input type="number" name="exclude_recent_days" min="0" value="0" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                            </label>
+                            <label class="block text-sm font-medium text-gray-700">题目数
+                                <input type="number" name="count" min="1" max="300" value="20" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                            </label>
+                            <label class="block text-sm font-medium text-gray-700">时间限制（分钟，0为不限时）
+                                <input type="number" name="duration" min="0" value="0" class="w-full border border-gray-300 rounded-md p-2 mt-1">
+                            </label>
+                        </div>
+                        <label class="inline-flex items-center"><input type="checkbox" name="allow_fewer" class="mr-1">题目不足时按实际数量组卷</label>
+                        <p id="custom-practice-preview" class="text-sm text-gray-600"></p>
+                        <div class="flex space-x-4">
+                            <button type="button" onclick="previewCustomPractice()" class="bg-secondary text-white px-6 py-2 rounded-lg hover:bg-gray-600">
+                                查看题量
+                            </button>
+                            <button type="submit" class="bg-primary text-white px-6 py-2 rounded-lg hover:bg-blue-600">
+                                开始练习
+                            </button>
+                        </div>
+                    </form>
+                    <div class="mt-6 text-center">
+                        <button onclick="showWelcomeContent()" class="text-gray-600 hover:text-gray-800">
+                            ← 返回首页
+                        </button>
+                    </div>
+                </div>
+            </div>
+        `;
     } catch (error) {
-        showMessage('开始考试失败', 'error');
+        showMessage('加载分类失败', 'error');
+    } finally {
+        showLoading(false);
+    }
+}
+
+// 读取自定义练习设置
+function customPracticeRequest() {
+    const form = document.getElementById('custom-practice-form');
+    return {
+        categories: Array.from(form.categories.selectedOptions).map(option => option.value),
+        types: Array.from(form.querySelectorAll('input[name="types"]:checked')).map(input => input.value),
+        tags: form.tags.value.split(/[,，]/).map(tag => tag.trim()).filter(tag => tag),
+        difficulty: form.difficulty.value.trim(),
+        only_unseen: form.only_unseen.checked,
+        only_wrong: form.only_wrong.checked,
+        only_bookmarked: form.only_bookmarked.checked,
+        exclude_recent_days: parseInt(form.exclude_recent_days.value) || 0,
+        count: parseInt(form.count.value) || 0,
+        duration: parseInt(form.duration.value) || 0,
+        allow_fewer: form.allow_fewer.checked
+    };
+}
+
+// 查看符合自定义练习条件的题目数
+async function previewCustomPractice() {
+    const preview = document.getElementById('custom-practice-preview');
+    try {
+        const response = await fetch(`${API_BASE}/practice/custom/preview`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'include',
+            body: JSON.stringify(customPracticeRequest())
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            preview.textContent = data.error;
+            return;
+        }
+        preview.textContent = data.enough
+            ? `共 ${data.available} 道题符合条件，将抽取 ${data.count} 道`
+            : `只有 ${data.available} 道题符合条件`;
+    } catch (error) {
+        preview.textContent = '统计题量失败';
+    }
+}
+
+// 按自定义条件开始练习
+async function startCustomPractice() {
+    try {
+        showLoading(true);
+        const response = await fetch(`${API_BASE}/practice/custom`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'include',
+            body: JSON.stringify(customPracticeRequest())
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            const message = data.available !== undefined
+                ? `符合条件的题目只有 ${data.available} 道，少于 ${data.requested} 道`
+                : data.error;
+            showMessage(message, 'error');
+            return;
+        }
+        beginExamSession(data);
+    } catch (error) {
+        showMessage('开始练习失败', 'error');
     } finally {
         showLoading(false);
     }
@@ -353,7 +726,7 @@ function showQuestionPage() {
                         </span>
                         <span class="ml-3 text-gray-600 text-sm">${escapeHtml(question.category)}</span>
                     </div>
-                    <h3 class="text-lg font-medium text-gray-900 mb-4">${escapeHtml(question.question)}</h3>
+                    <h3 class="text-lg font-medium text-gray-900 mb-4">${renderRichText(question.question)}</h3>
                     
                     <!-- 选项 -->
                     <div id="options-container">
@@ -402,6 +775,16 @@ function renderQuestionOptions(question, options) {
                     </label>
                 </div>
             `;
+        } else if (question.type === 'ordering' || question.type === 'matching') {
+            const placeholder = question.type === 'ordering' ?
+                '请按正确顺序输入选项字母，如 CABD' : '请输入编号与字母的配对，如 1B,2A,3C';
+            return `
+                <ul class="space-y-2 mb-4">
+                    ${(options || []).map(option => `<li>${renderRichText(option)}</li>`).join('')}
+                </ul>
+                <input type="text" name="answer" placeholder="${placeholder}"
+                       class="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-primary">
+            `;
         } else if (options && options.length > 0) {
             const inputType = question.type === 'multiple' ? 'checkbox' : 'radio';
             return `
@@ -409,14 +792,15 @@ function renderQuestionOptions(question, options) {
                     ${options.map((option, index) => `
                         <label class="flex items-center space-x-3 cursor-pointer">
                             <input type="${inputType}" name="answer" value="${escapeHtml(option)}" class="form-${inputType} text-primary">
-                            <span>${escapeHtml(option)}</span>
+                            <span>${renderRichText(option)}</span>
                         </label>
                     `).join('')}
                 </div>
             `;
         } else {
+            const placeholder = question.type === 'fill' ? '请输入答案，多个空以分号（;）分隔...' : '请输入答案...';
             return `
-                <textarea name="answer" rows="4" placeholder="请输入答案..." 
+                <textarea name="answer" rows="4" placeholder="${placeholder}" 
                           class="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-primary"></textarea>
             `;
         }
@@ -432,6 +816,10 @@ function getQuestionTypeText(type) {
         case 'single': return '单选题';
         case 'multiple': return '多选题';
         case 'judge': return '判断题';
+        case 'fill': return '填空题';
+        case 'short': return '简答题';
+        case 'ordering': return '排序题';
+        case 'matching': return '匹配题';
         default: return '题目';
     }
 }
@@ -449,7 +837,7 @@ function getUserAnswer() {
             return checked.value;
         }
         
-        const textarea = document.querySelector('textarea[name="answer"]');
+        const textarea = document.querySelector('textarea[name="answer"], input[type="text"][name="answer"]');
         if (textarea) {
             return textarea.value.trim();
         }
@@ -469,14 +857,16 @@ async function submitAnswer() {
     const question = AppState.currentQuestions[AppState.currentQuestionIndex];
     
     try {
-        const response = await fetch(`${API_BASE}/questions/submit`, {
+        const endpoint = AppState.isReviewMode ? 'review/answer' : 'questions/submit';
+        const response = await fetch(`${API_BASE}/${endpoint}`, {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
                 question_id: question.id,
-                answer: userAnswer
+                answer: userAnswer,
+                sequential_category: AppState.sequentialCategory || undefined
             }),
             credentials: 'include'
         });
@@ -532,12 +922,19 @@ async function submitExamAnswer() {
 function showAnswerResult(result) {
     const resultDiv = document.getElementById('answer-result');
     const isCorrect = result.is_correct;
+    const isPending = result.review_status === 'pending';
+    let resultText = isCorrect ? '✓ 回答正确' : '✗ 回答错误';
+    if (isPending) {
+        resultText = '⏳ 等待人工复核';
+    } else if (!isCorrect && result.score > 0) {
+        resultText = `✗ 部分正确（得分 ${Math.round(result.score * 100)}%）`;
+    }
     
     resultDiv.className = `mb-6 p-4 rounded-lg ${isCorrect ? 'bg-green-50 border border-green-200' : 'bg-red-50 border border-red-200'}`;
     resultDiv.innerHTML = `
         <div class="flex items-center mb-2">
             <span class="font-bold ${isCorrect ? 'text-green-800' : 'text-red-800'}">
-                ${isCorrect ? '✓ 回答正确' : '✗ 回答错误'}
+                ${resultText}
             </span>
         </div>
         <p class="${isCorrect ? 'text-green-700' : 'text-red-700'}">
@@ -548,6 +945,9 @@ function showAnswerResult(result) {
                 <strong>解析：</strong>${result.explanation}
             </p>
         ` : ''}
+        ${result.schedule ? `
+            <p class="mt-2 text-gray-600 text-sm">下次复习：${result.schedule.interval} 天后</p>
+        ` : ''}
     `;
     resultDiv.style.display = 'block';
     
@@ -661,11 +1061,16 @@ function showExamResult(result) {
     `;
 }
 
-// 显示错题本
-async function showWrongQuestions() {
+// 显示错题本，点击分类标签按分类筛选
+async function showWrongQuestions(offset = 0, category = '') {
+    const limit = 20;
     try {
         showLoading(true);
-        const response = await fetch(`${API_BASE}/questions/wrong`, {
+        const params = new URLSearchParams({ limit, offset });
+        if (category) {
+            params.set('category', category);
+        }
+        const response = await fetch(`${API_BASE}/questions/wrong?${params}`, {
             credentials: 'include'
         });
         
@@ -674,10 +1079,16 @@ async function showWrongQuestions() {
         }
         
         const data = await response.json();
+        // 移出最后一页的全部题目后回到上一页
+        if (data.wrong_questions.length === 0 && offset > 0) {
+            return showWrongQuestions(Math.max(offset - limit, 0), category);
+        }
+        AppState.wrongBookCategory = category;
+        AppState.wrongBookOffset = offset;
         
         const contentArea = document.getElementById('content-area');
         
-        if (data.wrong_questions.length === 0) {
+        if (data.total === 0 && !category) {
             contentArea.innerHTML = `
                 <div class="fade-in">
                     <div class="bg-white rounded-lg shadow-md p-8 text-center">
@@ -693,28 +1104,60 @@ async function showWrongQuestions() {
             return;
         }
         
+        const hasPrev = offset > 0;
+        const hasNext = offset + data.wrong_questions.length < data.total;
         contentArea.innerHTML = `
             <div class="fade-in">
                 <div class="bg-white rounded-lg shadow-md p-6">
-                    <h2 class="text-2xl font-bold text-gray-900 mb-6">错题本 (${data.total} 道题目)</h2>
+                    <div class="flex justify-between items-center mb-2">
+                        <h2 class="text-2xl font-bold text-gray-900">错题本 (${data.total} 道题目)</h2>
+                        <div class="flex items-center space-x-4">
+                            ${category ? `
+                                <button onclick="showWrongQuestions()" class="text-sm text-primary hover:text-blue-700">
+                                    ${escapeHtml(category)} ✕
+                                </button>
+                            ` : ''}
+                            <button onclick="startExam('wrong_questions')" class="bg-danger text-white px-4 py-2 rounded-lg hover:bg-red-600 text-sm">
+                                错题组卷
+                            </button>
+                        </div>
+                    </div>
+                    <p class="text-gray-500 text-sm mb-6">连续答对 ${data.graduation_streak} 次后自动移出错题本</p>
                     <div class="space-y-4">
-                        ${data.wrong_questions.map((item, index) => `
+                        ${data.wrong_questions.map(item => `
                             <div class="border border-gray-200 rounded-lg p-4">
                                 <div class="flex justify-between items-start mb-2">
-                                    <span class="bg-red-100 text-red-800 px-2 py-1 rounded text-sm">${item.category}</span>
-                                    <span class="text-gray-500 text-sm">${new Date(item.answered_at).toLocaleDateString()}</span>
+                                    <button onclick="showWrongQuestions(0, this.dataset.category)" data-category="${escapeHtml(item.category).replace(/"/g, '&quot;')}"
+                                            class="bg-red-100 text-red-800 px-2 py-1 rounded text-sm hover:bg-red-200">${escapeHtml(item.category)}</button>
+                                    <span class="text-gray-500 text-sm">
+                                        错 ${item.wrong_count} 次 · 最近 ${new Date(item.last_wrong_at).toLocaleDateString()}
+                                    </span>
                                 </div>
-                                <h3 class="font-medium text-gray-900 mb-2">${item.question}</h3>
+                                <h3 class="font-medium text-gray-900 mb-2">${escapeHtml(item.question)}</h3>
                                 <div class="text-sm">
-                                    <p class="text-red-600 mb-1"><strong>您的答案：</strong>${item.user_answer || '未作答'}</p>
-                                    <p class="text-green-600"><strong>正确答案：</strong>${item.correct_answer}</p>
+                                    <p class="text-red-600 mb-1"><strong>您的答案：</strong>${escapeHtml(item.user_answer) || '未作答'}</p>
+                                    <p class="text-green-600"><strong>正确答案：</strong>${escapeHtml(item.correct_answer)}</p>
+                                </div>
+                                <div class="flex justify-between items-center mt-3 text-sm">
+                                    <span class="text-gray-500">已连续答对 ${item.correct_streak}/${data.graduation_streak}</span>
+                                    <button onclick="removeWrongQuestion(${item.question_id})" class="text-gray-500 hover:text-red-600">
+                                        移出错题本
+                                    </button>
                                 </div>
                             </div>
                         `).join('')}
                     </div>
-                    <div class="mt-6 text-center">
+                    <div class="mt-6 flex justify-between items-center">
+                        <button onclick="showWrongQuestions(${Math.max(offset - limit, 0)}, AppState.wrongBookCategory)"
+                                class="text-primary hover:text-blue-700 ${hasPrev ? '' : 'invisible'}">
+                            ← 上一页
+                        </button>
                         <button onclick="showWelcomeContent()" class="text-gray-600 hover:text-gray-800">
-                            ← 返回首页
+                            返回首页
+                        </button>
+                        <button onclick="showWrongQuestions(${offset + limit}, AppState.wrongBookCategory)"
+                                class="text-primary hover:text-blue-700 ${hasNext ? '' : 'invisible'}">
+                            下一页 →
                         </button>
                     </div>
                 </div>
@@ -727,6 +1170,25 @@ async function showWrongQuestions() {
     }
 }
 
+// 手动将题目移出错题本
+async function removeWrongQuestion(questionId) {
+    try {
+        const response = await fetch(`${API_BASE}/questions/${questionId}/wrong`, {
+            method: 'DELETE',
+            credentials: 'include'
+        });
+        
+        if (!response.ok) {
+            throw new Error('Failed to remove wrong question');
+        }
+        
+        showMessage('已移出错题本', 'success');
+        showWrongQuestions(AppState.wrongBookOffset || 0, AppState.wrongBookCategory || '');
+    } catch (error) {
+        showMessage('移出错题本失败', 'error');
+    }
+}
+
 // 显示学习统计
 async function showStats() {
     try {
@@ -741,6 +1203,15 @@ async function showStats() {
         
         const data = await response.json();
         
+        // 薄弱分类加载失败时不影响统计显示
+        let weakAreas = [];
+        const weakResponse = await fetch(`${API_BASE}/user/weak-areas`, {
+            credentials: 'include'
+        });
+        if (weakResponse.ok) {
+            weakAreas = (await weakResponse.json()).weak_areas;
+        }
+        
         const contentArea = document.getElementById('content-area');
         contentArea.innerHTML = `
             <div class="fade-in">
@@ -763,21 +1234,63 @@ async function showStats() {
                         </div>
                     </div>
                     
+                    <!-- 题库覆盖 -->
+                    ${data.coverage ? `
+                        <div class="mb-8">
+                            <h3 class="text-lg font-semibold text-gray-900 mb-2">题库覆盖</h3>
+                            <div class="flex justify-between text-sm text-gray-600 mb-1">
+                                <span>已做过 ${data.coverage.seen} / ${data.coverage.total} 题，还有 ${data.coverage.unseen} 题未做</span>
+                                <span>${data.coverage.coverage.toFixed(1)}%</span>
+                            </div>
+                            <div class="w-full bg-gray-200 rounded-full h-3 mb-4">
+                                <div class="bg-success h-3 rounded-full" style="width: ${data.coverage.coverage}%"></div>
+                            </div>
+                            <div class="grid grid-cols-2 md:grid-cols-4 gap-2 text-sm">
+                                ${data.coverage.types.map(type => `
+                                    <div class="p-2 bg-gray-50 rounded">
+                                        <span class="text-gray-900">${escapeHtml(type.name)}</span>
+                                        <span class="text-gray-600 ml-1">${type.seen}/${type.total}</span>
+                                    </div>
+                                `).join('')}
+                            </div>
+                        </div>
+                    ` : ''}
+                    
+                    <!-- 薄弱分类 -->
+                    ${weakAreas.length > 0 ? `
+                        <div class="mb-8">
+                            <h3 class="text-lg font-semibold text-gray-900 mb-4">建议加强</h3>
+                            <div class="space-y-2">
+                                ${weakAreas.map(area => `
+                                    <div class="flex items-center justify-between p-3 bg-red-50 rounded-lg">
+                                        <div>
+                                            <span class="font-medium text-gray-900">${escapeHtml(area.category)}</span>
+                                            <span class="text-gray-600 text-sm ml-2">掌握程度 ${area.mastery.toFixed(1)}%${area.unseen > 0 ? `，还有 ${area.unseen} 题没做过` : ''}</span>
+                                        </div>
+                                        <button onclick="startCategoryPractice(this.dataset.category)" data-category="${escapeHtml(area.category).replace(/"/g, '&quot;')}"
+                                                class="text-sm text-primary hover:text-blue-700">去练习 →</button>
+                                    </div>
+                                `).join('')}
+                            </div>
+                        </div>
+                    ` : ''}
+                    
                     <!-- 分类统计 -->
                     <div class="mb-6">
-                        <h3 class="text-lg font-semibold text-gray-900 mb-4">分类统计</h3>
+                        <h3 class="text-lg font-semibold text-gray-900 mb-4">分类统计 <span class="text-sm font-normal text-gray-500">（进度条为按近期表现估计的掌握程度）</span></h3>
                         <div class="space-y-3">
                             ${data.category_stats && data.category_stats.length > 0 ? data.category_stats.map(stat => `
                                 <div class="flex items-center justify-between p-3 bg-gray-50 rounded-lg">
                                     <div>
                                         <span class="font-medium text-gray-900">${stat.category}</span>
-                                        <span class="text-gray-600 text-sm ml-2">(${stat.correct}/${stat.total})</span>
+                                        <span class="text-gray-600 text-sm ml-2">(${stat.correct}/${stat.total}，正确率 ${(stat.accuracy || 0).toFixed(1)}%)</span>
+                                        ${stat.mastered ? '<span class="bg-green-100 text-green-800 px-2 py-0.5 rounded text-xs ml-2">已掌握</span>' : ''}
                                     </div>
-                                    <div class="flex items-center space-x-3">
+                                    <div class="flex items-center space-x-3" title="掌握程度">
                                         <div class="w-32 bg-gray-200 rounded-full h-2">
-                                            <div class="bg-primary h-2 rounded-full" style="width: ${stat.accuracy || 0}%"></div>
+                                            <div class="bg-primary h-2 rounded-full" style="width: ${stat.mastery || 0}%"></div>
                                         </div>
-                                        <span class="text-sm font-medium text-gray-900 w-12">${(stat.accuracy || 0).toFixed(1)}%</span>
+                                        <span class="text-sm font-medium text-gray-900 w-12">${(stat.mastery || 0).toFixed(1)}%</span>
                                     </div>
                                 </div>
                             `).join('') : '<p class="text-gray-500 text-center py-4">暂无统计数据</p>'}